@@ -0,0 +1,78 @@
+package bot
+
+import (
+	"fmt"
+	"github.com/bwmarrin/discordgo"
+	"github.com/gontikr99/bidbot2/controller/discord"
+	"github.com/gontikr99/bidbot2/controller/everquest"
+	"github.com/gontikr99/bidbot2/controller/storage"
+	"log"
+	"strings"
+	"time"
+)
+
+// The periods attendance is reported over, in days
+var attendancePeriods = []int{30, 60, 90}
+
+// Parse a raid dump and store it for attendance tracking
+func recordRaidDump(taken time.Time, raidDump []byte) {
+	members := everquest.ParseRaidDump(raidDump)
+	if len(members) == 0 {
+		log.Println("Raid dump had no members in it, not recording attendance")
+		return
+	}
+	err := storage.SaveRaidSnapshot(&storage.RaidSnapshot{Taken: taken, Members: members})
+	if err != nil {
+		log.Printf("Failed to record attendance: %v", err)
+	}
+}
+
+// Describe a character's attendance over each of the attendance periods, e.g.
+// "Alice attended 80% (8/10) of raid nights in the last 30 days, ..."
+func describeAttendance(charname string) (string, error) {
+	parts := make([]string, 0, len(attendancePeriods))
+	for _, days := range attendancePeriods {
+		attended, nights, err := storage.Attendance(charname, days)
+		if err != nil {
+			return "", err
+		}
+		if nights == 0 {
+			parts = append(parts, fmt.Sprintf("no raids in %d days", days))
+			continue
+		}
+		parts = append(parts, fmt.Sprintf("%d%% (%d/%d) in %d days", attended*100/nights, attended, nights, days))
+	}
+	return fmt.Sprintf("%v raid night attendance: %v", inicap(charname), strings.Join(parts, ", ")), nil
+}
+
+func RegisterAttendanceCommands(eqc *everquest.Client, dc *discord.Client) {
+	eqc.RegisterTellCommand("!attendance", func(who string, args string) {
+		charname := strings.TrimSpace(args)
+		if charname == "" {
+			charname = who
+		}
+		text, err := describeAttendance(charname)
+		if err != nil {
+			log.Printf("Failed to look up attendance: %v", err)
+			logOnError(eqc.Tell(who, "An error occurred, sorry."))
+			return
+		}
+		logOnError(eqc.Tell(who, text))
+	})
+
+	dc.RegisterDiscordCommand("!attendance", func(msg *discordgo.MessageCreate, args string) {
+		dc.Fade(msg.Message)
+		charname := strings.TrimSpace(args)
+		if charname == "" {
+			dc.ReplyError(msg, "attendance", "Whose attendance did you want?")
+			return
+		}
+		text, err := describeAttendance(charname)
+		if err != nil {
+			dc.ReplyError(msg, "attendance", "An error occurred looking up attendance, sorry.")
+			log.Printf("Failed to look up attendance: %v", err)
+			return
+		}
+		dc.ReplyOK(msg, "attendance", text)
+	})
+}
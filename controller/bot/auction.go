@@ -7,18 +7,29 @@ import (
 	"github.com/gontikr99/bidbot2/controller/assets"
 	"github.com/gontikr99/bidbot2/controller/discord"
 	"github.com/gontikr99/bidbot2/controller/everquest"
+	"github.com/gontikr99/bidbot2/controller/everquest/events"
+	"github.com/gontikr99/bidbot2/controller/items"
 	"github.com/gontikr99/bidbot2/controller/plugin"
+	"github.com/gontikr99/bidbot2/controller/storage"
 	"log"
+	"math"
 	"regexp"
+	"sort"
 	"strconv"
 	"strings"
 	"sync"
-	"sync/atomic"
 	"time"
 )
 
-var tellRE = regexp.MustCompile("^([A-Za-z]+) (?:tells|told) you, '(.*)'$")
 var numRE = regexp.MustCompile("^([-+]?(?:[0-9]*\\.?[0-9]+))(?:[^0-9].*)?$")
+var countRE = regexp.MustCompile("^([0-9]+)[xX]\\s+(.*)$")
+var indexedBidRE = regexp.MustCompile("^([0-9]+)\\s+(.*)$")
+var allInRE = regexp.MustCompile("^(?i:all|max)(?:[^A-Za-z0-9].*)?$")
+var cancelRE = regexp.MustCompile("^(?i:cancel)(?:[^A-Za-z0-9].*)?$")
+var openRE = regexp.MustCompile("^\\s*(?i:open)\\s+(.*)$")
+
+// Separates the items in a request to auction several items at once
+const itemSeparator = "|"
 
 type bidEntry struct {
 	Bidder   string
@@ -26,9 +37,88 @@ type bidEntry struct {
 	MsgEntry *discordgo.Message
 }
 
-type allBids struct {
-	bidTexts []bidEntry
+// One of the items being auctioned, along with the bids collected for it
+type auctionItem struct {
+	Index  int
+	Name   string
+	Escape string
+	Count  int
+	Link   func(everquest.EqInput)
+	Info   *items.Item // From the item database, or nil if the item isn't known
+
 	bids     map[string]float64
+	bidTexts []storage.BidText
+	record   *storage.AuctionRecord
+}
+
+// The text naming this item in EverQuest announcements, e.g. "2: 3x <link>"
+func (ai *auctionItem) eqText(numbered bool) []interface{} {
+	prefix := countPrefix(ai.Count)
+	if numbered {
+		prefix = strconv.Itoa(ai.Index) + ": " + prefix
+	}
+	return []interface{}{prefix, ai.Link}
+}
+
+// The text naming this item in Discord, e.g. "2: 3x `Cloak of Flames`"
+func (ai *auctionItem) dcText(numbered bool) string {
+	prefix := countPrefix(ai.Count)
+	if numbered {
+		prefix = strconv.Itoa(ai.Index) + ": " + prefix
+	}
+	return prefix + "`" + ai.Escape + "`"
+}
+
+// Details of the item from the item database, formatted as an extra line for Discord
+func (ai *auctionItem) dcInfo() string {
+	if ai.Info == nil || ai.Info.Summary() == "" {
+		return ""
+	}
+	if !strings.EqualFold(ai.Info.Name, ai.Name) {
+		return "\n" + ai.Info.Name + ": " + ai.Info.Summary()
+	}
+	return "\n" + ai.Info.Summary()
+}
+
+// The highest bid on this item so far, and who placed it
+func (ai *auctionItem) highBid() (bidder string, bid float64) {
+	for eachBidder, eachBid := range ai.bids {
+		if bidder == "" || eachBid > bid {
+			bidder, bid = eachBidder, eachBid
+		}
+	}
+	return
+}
+
+// Everything we know about an auction while it runs
+type auction struct {
+	eqc *everquest.Client
+	dc  *discord.Client
+	gp  *plugin.GuildPlugin
+
+	ctx    context.Context
+	cancel func()
+
+	// Held while bids are being processed, and while the auction is being changed by C&C commands
+	sync sync.Mutex
+
+	request  *storage.QueuedAuction
+	start    time.Time
+	items    []*auctionItem
+	bidTexts []bidEntry
+	schedule *storage.AuctionSchedule
+	timeline *timeline
+
+	// Where the auction is being held, and the boss the loot probably came from, if known
+	zone string
+	boss string
+
+	// When only raid members may bid, the characters in the raid and their mains.  nil if anyone may bid.
+	raidChars map[string]bool
+	raidMains map[string]bool
+
+	// Held while announcing a new high bid in an open auction, so announcements don't overlap
+	announceSync sync.Mutex
 }
 
 func logOnError(args ...interface{}) {
@@ -53,6 +143,71 @@ func announce(eqc *everquest.Client, dc *discord.Client, eqText []interface{}, d
 	wg.Wait()
 }
 
+// Split a request to auction several items (separated by `itemSeparator`) into the individual items
+func parseAuctionItems(args string) []storage.QueuedItem {
+	result := make([]storage.QueuedItem, 0)
+	for _, part := range strings.Split(args, itemSeparator) {
+		count, itemName := parseItemCount(part)
+		if itemName != "" {
+			result = append(result, storage.QueuedItem{ItemName: itemName, Count: count})
+		}
+	}
+	return result
+}
+
+// BidBot2's own understanding of a bid: a number, "all" (or "all in", "max"), or "cancel"
+func parseBidText(text string) *plugin.ParsedBid {
+	text = strings.TrimSpace(text)
+	if cancelRE.MatchString(text) {
+		return &plugin.ParsedBid{Action: plugin.BidCancel}
+	}
+	if allInRE.MatchString(text) {
+		return &plugin.ParsedBid{Action: plugin.BidAllIn}
+	}
+	parts := numRE.FindStringSubmatch(text)
+	if parts == nil {
+		return &plugin.ParsedBid{Action: plugin.BidReject}
+	}
+	amount, err := strconv.ParseFloat(parts[1], 64)
+	if err != nil {
+		return &plugin.ParsedBid{Action: plugin.BidReject, Reason: "Sorry, I had a problem understanding your bid."}
+	}
+	if amount == 0 {
+		return &plugin.ParsedBid{Action: plugin.BidCancel}
+	}
+	return &plugin.ParsedBid{Action: plugin.BidAmount, Amount: amount}
+}
+
+// Split the optional auction mode (e.g. "open Cloak of Flames") off of an auction request
+func parseAuctionMode(args string) (open bool, itemArgs string) {
+	if parts := openRE.FindStringSubmatch(args); parts != nil {
+		return true, parts[1]
+	}
+	return false, args
+}
+
+// Split an optional quantity prefix (e.g. "3x Cloak of Flames") off of the item name
+func parseItemCount(args string) (count int, itemName string) {
+	itemName = strings.TrimSpace(args)
+	parts := countRE.FindStringSubmatch(itemName)
+	if parts == nil {
+		return 1, itemName
+	}
+	count, err := strconv.Atoi(parts[1])
+	if err != nil || count < 1 {
+		return 1, itemName
+	}
+	return count, strings.TrimSpace(parts[2])
+}
+
+// Describe how many of an item are up for auction, e.g. "3x " or nothing for a single item
+func countPrefix(count int) string {
+	if count == 1 {
+		return ""
+	}
+	return fmt.Sprintf("%dx ", count)
+}
+
 // Call into the plugin to get a solicitation for the item
 func solicit(gp *plugin.GuildPlugin, itemName string) string {
 	solText, err := gp.Solicit(itemName)
@@ -64,241 +219,649 @@ func solicit(gp *plugin.GuildPlugin, itemName string) string {
 	}
 }
 
-func RegisterAuctionCommand(eqc *everquest.Client, dc *discord.Client, gp *plugin.GuildPlugin) {
-	auctionRunning := uint32(0)
-	eqc.RegisterCCCommand("!auc", func(who string, args string) {
-		if !atomic.CompareAndSwapUint32(&auctionRunning, 0, 1) {
-			logOnError(eqc.Tellf(who, "Currently running an auction, please try later"))
-			return
-		}
-		defer func() {
-			atomic.StoreUint32(&auctionRunning, 0)
-		}()
+func newAuction(eqc *everquest.Client, dc *discord.Client, gp *plugin.GuildPlugin, enc *Encounters, qa *storage.QueuedAuction) *auction {
+	a := &auction{
+		eqc:      eqc,
+		dc:       dc,
+		gp:       gp,
+		request:  qa,
+		start:    time.Now(),
+		items:    make([]*auctionItem, 0, len(qa.Items)),
+		bidTexts: make([]bidEntry, 0),
+		zone:     enc.Zone(),
+		boss:     enc.RecentBoss(),
+	}
+	a.ctx, a.cancel = context.WithCancel(eqc.Context)
+	for idx, qi := range qa.Items {
+		a.items = append(a.items, &auctionItem{
+			Index:  idx + 1,
+			Name:   qi.ItemName,
+			Escape: strings.ReplaceAll(qi.ItemName, "`", "'"),
+			Count:  qi.Count,
+			Link:   everquest.PlainLink(qi.ItemName),
+			Info:   items.Find(qi.ItemName),
 
-		// Find item link
-		itemName := strings.TrimSpace(args)
-		if len(itemName) == 0 {
-			logOnError(eqc.Tell(who, "What did you want me to auction?"))
-			return
-		}
-		itemEscape := strings.ReplaceAll(itemName, "`", "'")
-		logOnError(eqc.Tellf(who, "Starting auction on %v", itemName))
-		itemOffset := strings.Index(args, itemName)
-		spaces := args[:itemOffset]
-		itemLink, err := eqc.RaiseLink("!auc" + spaces + "{" + itemName + "}")
+			bids:     make(map[string]float64),
+			bidTexts: make([]storage.BidText, 0),
+		})
+	}
+	return a
+}
+
+// Run the auction to completion.  `linkText` is the text of the C&C message the auction was requested
+// in, if that message is still expected to be the most recent one on screen; otherwise it's empty, and the
+// item names are typed in place of links.
+func (a *auction) run(linkText string) {
+	eqc, dc := a.eqc, a.dc
+	defer a.cancel()
+	who := a.request.RequestedBy
+	if len(a.items) == 0 {
+		return
+	}
+	numbered := len(a.items) > 1
+	logOnError(eqc.Tellf(who, "Starting auction on %v", a.itemNames()))
+
+	// Only a lone item can be linked: we can't tell apart the item windows of several links.
+	if linkText != "" && !numbered {
+		itemLink, err := eqc.RaiseLink(linkText)
 		if err != nil {
 			logOnError(eqc.Tellf(who, "I couldn't find the item window.  Did you send a link?"))
 			return
 		}
+		a.items[0].Link = itemLink
 		defer func() { logOnError(eqc.ClearWindows()) }()
+	}
 
-		// Setup to collect bids
-		logMessages, tapDone := eqc.TapLog()
-		_, err = dc.Writef("---- [%v] **Bid Start**: `%v`", who, itemEscape)
+	// Setup to collect bids
+	tells, tapDone := eqc.Subscribe(events.TellType)
+	_, err := dc.Writef("---- [%v] **Bid Start**: %v", who, a.dcItemNames())
+	if err != nil {
+		log.Println(err)
+		logOnError(eqc.Tellf(who, "Failed to send initial message to discord: %v", err))
+		return
+	}
+	raidDump, err := eqc.RaidDump()
+	if err == nil && len(raidDump) != 0 {
+		logOnError(dc.Upload("raiddump.txt", raidDump))
+	}
+	a.restrictToRaid(raidDump)
+	schedule := a.chooseSchedule()
+	a.sync.Lock()
+	a.schedule = schedule
+	a.timeline = newTimeline(a.openingDuration())
+	a.sync.Unlock()
+	resultChan := make(chan struct{})
+	subCtx, subDone := context.WithCancel(a.ctx)
+	go func() {
+		defer tapDone()
+		for {
+			select {
+			case <-subCtx.Done():
+				resultChan <- struct{}{}
+				return
+			case event := <-tells:
+				tell := event.(*events.Tell)
+				if !eqc.IsBotLog(tell.LogEntry) {
+					continue
+				}
+				teller := strings.ToLower(tell.From)
+				tellMsg := tell.Text
+				if strings.HasPrefix(tellMsg, "!") || strings.Contains(tellMsg, "A.F.K.") || strings.Contains(tellMsg, "AFK Message") {
+					continue
+				}
+				dmsg, err := dc.Writef("`%v` sent me a tell", inicap(teller))
+				if err != nil {
+					log.Println(err)
+				} else {
+					a.bidTexts = append(a.bidTexts, bidEntry{
+						Bidder:   teller,
+						BidText:  tellMsg,
+						MsgEntry: dmsg,
+					})
+				}
+				a.sync.Lock()
+				a.handleBid(teller, tellMsg)
+				a.sync.Unlock()
+			}
+		}
+	}()
+
+	// Talk while we've got the collector running in the background
+	runTimeline := a.runTimeline
+	if a.request.Open {
+		runTimeline = a.runOpenTimeline
+	}
+	func() {
+		defer subDone()
+		for runTimeline(a.timeline.currentGeneration()) == waitRestarted {
+		}
+	}()
+	<-resultChan
+	if a.ctx.Err() == nil {
+		for _, item := range a.items {
+			a.settle(item, numbered)
+		}
+	}
+	for _, toUpdate := range a.bidTexts {
+		go func(updateEntry bidEntry) {
+			logOnError(dc.Session.ChannelMessageEdit(
+				updateEntry.MsgEntry.ChannelID,
+				updateEntry.MsgEntry.ID,
+				fmt.Sprintf("`%v:` %v", inicap(updateEntry.Bidder), updateEntry.BidText)))
+		}(toUpdate)
+	}
+}
+
+// Make the announcements for one run through the auction's timeline, from the opening bell to the close
+func (a *auction) runTimeline(generation int) waitResult {
+	eqc, dc := a.eqc, a.dc
+	err := dc.Play(assets.BellTone())
+	if err != nil {
+		log.Println(err)
+	}
+	announce(eqc, dc,
+		a.eqAnnouncement(">> Bidding starts on ", ", "+a.bidInstructions(true)+".  "+secondsText(a.timeline.remaining())+" remain. <<"),
+		a.solicit()+".  "+secondsText(a.timeline.remaining())+" to go!")
+	warnings := a.schedule.SortedWarnings()
+	for idx, warning := range warnings {
+		if result := a.timeline.waitUntil(a.ctx, warning, generation); result != waitReached {
+			return result
+		}
+		left := secondsText(a.timeline.remaining())
+		dcText := a.solicit() + ".  " + left + " to go!"
+		if idx == len(warnings)-1 {
+			dcText = a.solicit() + ".  Last call!"
+		}
+		announce(eqc, dc,
+			a.eqAnnouncement(">> Bid for ", ", "+a.bidInstructions(false)+".  "+left+" remain. <<"),
+			dcText)
+	}
+	return a.closeTimeline(generation)
+}
+
+// Make the announcements for an open auction, which runs until nobody has raised the high bid for a while
+func (a *auction) runOpenTimeline(generation int) waitResult {
+	eqc, dc := a.eqc, a.dc
+	logOnError(dc.Play(assets.BellTone()))
+	idle := secondsText(a.schedule.OpenIdle)
+	announce(eqc, dc,
+		a.eqAnnouncement(">> Open bidding starts on ", ", "+a.bidInstructions(true)+".  Each new high bid is announced, and bidding closes after "+idle+" without one. <<"),
+		a.solicit()+".  Open bidding, closing after "+idle+" without a new high bid!")
+	return a.closeTimeline(generation)
+}
+
+// Wait for the end of the timeline, then close bidding
+func (a *auction) closeTimeline(generation int) waitResult {
+	eqc, dc := a.eqc, a.dc
+	if result := a.timeline.waitUntil(a.ctx, 0, generation); result != waitReached {
+		return result
+	}
+	if !a.timeline.close(generation) {
+		if a.ctx.Err() != nil || a.timeline.isStopped() {
+			return waitCancelled
+		}
+		return waitRestarted
+	}
+
+	logOnError(dc.Play(assets.BellTone()))
+	announce(eqc, dc,
+		a.eqAnnouncement(">> Bidding closed for ", " <<"),
+		"No more bids for "+a.itemNames()+".")
+	return waitReached
+}
+
+// How long the timeline runs when the auction starts or restarts
+func (a *auction) openingDuration() time.Duration {
+	if a.request.Open {
+		return a.schedule.OpenIdle
+	}
+	return a.schedule.Duration
+}
+
+// Build an EverQuest announcement naming every item in the auction
+func (a *auction) eqAnnouncement(before string, after string) []interface{} {
+	numbered := len(a.items) > 1
+	result := []interface{}{before}
+	for idx, item := range a.items {
+		if idx != 0 {
+			result = append(result, ", ")
+		}
+		result = append(result, item.eqText(numbered)...)
+	}
+	return append(result, after)
+}
+
+// Tell bidders how to bid, which depends on whether they have to pick an item
+func (a *auction) bidInstructions(first bool) string {
+	if len(a.items) > 1 {
+		if first {
+			return "send me the item number and your bid, e.g. '2 150' (to see your posted total send me !dkp)"
+		}
+		return "send me the item number and your bid, e.g. '2 150'"
+	}
+	if first {
+		return "send me a number (to see your posted total send me !dkp)"
+	}
+	return "send me a number (and only a number)"
+}
+
+// Describe an amount of time left in an auction, e.g. "30 seconds"
+func secondsText(d time.Duration) string {
+	return fmt.Sprintf("%d seconds", int((d+time.Second/2)/time.Second))
+}
+
+// Pick the schedule for the auction.  When several items are auctioned together, the longest one wins.
+func (a *auction) chooseSchedule() *storage.AuctionSchedule {
+	defaultSchedule := a.eqc.Config.AuctionSchedule()
+	result := defaultSchedule
+	for idx, item := range a.items {
+		schedule, err := a.gp.AuctionSchedule(item.Name, item.Count, defaultSchedule)
 		if err != nil {
 			log.Println(err)
-			logOnError(eqc.Tellf(who, "Failed to send initial message to discord: %v", err))
-			return
+			continue
 		}
-		raidDump, err := eqc.RaidDump()
-		if err == nil && len(raidDump)!=0 {
-			logOnError(dc.Upload("raiddump.txt", raidDump))
+		if idx == 0 || schedule.Duration > result.Duration {
+			result = schedule
 		}
-		resultChan := make(chan *allBids)
-		subCtx, subDone := context.WithCancel(eqc.Context)
-		go func() {
-			defer tapDone()
-			result := &allBids{
-				bidTexts: make([]bidEntry, 0),
-				bids:     make(map[string]float64),
-			}
-			for {
-				select {
-				case <-subCtx.Done():
-					resultChan <- result
-					return
-				case msg := <-logMessages:
-					matchTell := tellRE.FindStringSubmatch(msg.Message)
-					if matchTell == nil {
-						continue
-					}
-					teller := strings.ToLower(matchTell[1])
-					tellMsg := matchTell[2]
-					if strings.HasPrefix(tellMsg, "!") || strings.Contains(tellMsg, "A.F.K.") || strings.Contains(tellMsg, "AFK Message") {
-						continue
-					}
-					dmsg, err := dc.Writef("`%v` sent me a tell", inicap(teller))
-					if err != nil {
-						log.Println(err)
-					} else {
-						result.bidTexts = append(result.bidTexts, bidEntry{
-							Bidder:   teller,
-							BidText:  tellMsg,
-							MsgEntry: dmsg,
-						})
-					}
-					numRE := numRE.FindStringSubmatch(tellMsg)
-					if numRE == nil {
-						go func() {
-							logOnError(eqc.Tellf(teller, "You told me '%v', and I can't make any sense of that as a bid.", tellMsg))
-							logOnError(eqc.Tellf(teller, "Please send me your bid as a number, or 0 to cancel a previous bid."))
-						}()
-						continue
-					}
-					bidValue, err := strconv.ParseFloat(numRE[1], 64)
-					if err != nil {
-						log.Println(err)
-						go func() { logOnError(eqc.Tellf(teller, "Sorry, I had a problem understanding your bid.")) }()
-						continue
-					}
-					if bidValue == 0 {
-						if _, ok := result.bids[teller]; ok {
-							delete(result.bids, teller)
-							go func() { logOnError(eqc.Tellf(teller, "Cancelled your bid.")) }()
-						} else {
-							go func() { logOnError(eqc.Tellf(teller, "You haven't placed a bid yet!")) }()
-						}
-						continue
-					}
-					errmsg, err := gp.ValidateBid(teller, bidValue)
-					if err != nil {
-						log.Println(err)
-						go func() { logOnError(eqc.Tellf(teller, "Sorry, I had a problem validating your bid.")) }()
-						continue
-					}
-					if errmsg != "" {
-						go func() { logOnError(eqc.Tell(teller, errmsg)) }()
-						continue
-					}
-					prevBid, hadPrev := result.bids[teller]
-					result.bids[teller] = bidValue
-					dkpTotal, err := gp.GetDKP(teller)
-					if err == nil && bidValue > dkpTotal {
-						go func() {
-							logOnError(eqc.Tellf(teller, "Entering your bid of %v on %v, even though you only have %v DKP.  Send 0 to cancel.",
-								bidValue, itemName, dkpTotal))
-						}()
-					} else {
-						if err != nil {
-							log.Println(err)
-						}
-						if hadPrev {
-							go func() {
-								logOnError(eqc.Tellf(teller, "Received your bid of %v on %v, replacing your previous bid of %v.  Send 0 to cancel.", bidValue, itemName, prevBid))
-							}()
-						} else {
-							go func() {
-								logOnError(eqc.Tellf(teller, "Received your bid of %v on %v.  Send 0 to cancel.", bidValue, itemName))
-							}()
-						}
-					}
-				}
-			}
-		}()
+	}
+	return result
+}
 
-		// Talk while we've got the collector running in the background
-		func() {
-			defer subDone()
-			err = dc.Play(assets.BellTone())
-			if err != nil {
-				log.Println(err)
-			}
-			announce(eqc, dc,
-				[]interface{}{">> Bidding starts on ", itemLink, ", send me a number (to see your posted total send me !dkp).  60 seconds remain. <<"},
-				solicit(gp, itemEscape)+".  60 seconds to go!")
-			select {
-			case <-eqc.Context.Done():
-				return
-			case <-time.After(30 * time.Second):
-				break
-			}
+// Extend the auction if a new high bid came in too close to the end
+func (a *auction) checkSnipe(item *auctionItem) {
+	if a.schedule.SnipeWindow <= 0 || a.schedule.SnipeExtend <= 0 {
+		return
+	}
+	if a.timeline.remaining() > a.schedule.SnipeWindow {
+		return
+	}
+	if !a.timeline.extendTo(a.schedule.SnipeExtend) {
+		return
+	}
+	left := secondsText(a.schedule.SnipeExtend)
+	go announce(a.eqc, a.dc,
+		append(append([]interface{}{">> New high bid, extending bidding on "}, item.eqText(len(a.items) > 1)...),
+			".  "+left+" remain. <<"),
+		"New high bid on "+countPrefix(item.Count)+item.Escape+".  "+left+" to go!")
+}
 
-			announce(eqc, dc,
-				[]interface{}{">> Bid for ", itemLink, ", send me a number (and only a number).  30 seconds remain. <<"},
-				solicit(gp, itemEscape)+".  30 seconds to go!")
-			select {
-			case <-eqc.Context.Done():
-				return
-			case <-time.After(20 * time.Second):
-				break
+// The names of the items in the auction, as plain text
+func (a *auction) itemNames() string {
+	names := make([]string, 0, len(a.items))
+	for _, item := range a.items {
+		names = append(names, countPrefix(item.Count)+item.Escape)
+	}
+	return strings.Join(names, ", ")
+}
+
+// The names of the items in the auction, formatted for Discord
+func (a *auction) dcItemNames() string {
+	names := make([]string, 0, len(a.items))
+	for _, item := range a.items {
+		names = append(names, item.dcText(len(a.items) > 1))
+	}
+	return strings.Join(names, ", ")
+}
+
+// The spoken solicitation for the auction
+func (a *auction) solicit() string {
+	if len(a.items) == 1 {
+		return solicit(a.gp, countPrefix(a.items[0].Count)+a.items[0].Escape)
+	}
+	return "Bids on " + a.itemNames()
+}
+
+// Figure out which item a tell is bidding on, and what's left of the tell once the item number is removed
+func (a *auction) findItem(tellMsg string) (item *auctionItem, bidText string) {
+	if parts := indexedBidRE.FindStringSubmatch(tellMsg); parts != nil {
+		if index, err := strconv.Atoi(parts[1]); err == nil && index >= 1 && index <= len(a.items) {
+			if len(a.items) > 1 || parseBidText(parts[2]).Action != plugin.BidReject {
+				return a.items[index-1], parts[2]
 			}
+		}
+	}
+	if len(a.items) == 1 {
+		return a.items[0], tellMsg
+	}
+	return nil, tellMsg
+}
 
-			announce(eqc, dc,
-				[]interface{}{">> Bid for ", itemLink, ", send me a number (and only a number).  10 seconds remain. <<"},
-				solicit(gp, itemEscape)+".  Last call!")
-			select {
-			case <-eqc.Context.Done():
+// Process a tell received while the auction is running
+func (a *auction) handleBid(teller string, tellMsg string) {
+	eqc, gp := a.eqc, a.gp
+	item, bidText := a.findItem(tellMsg)
+	rawText := storage.BidText{Bidder: teller, Text: tellMsg, Received: time.Now()}
+	if item == nil {
+		for _, eachItem := range a.items {
+			eachItem.bidTexts = append(eachItem.bidTexts, rawText)
+		}
+	} else {
+		item.bidTexts = append(item.bidTexts, rawText)
+	}
+	if !a.mayBid(teller) {
+		go func() { logOnError(eqc.Tell(teller, notInRaidText)) }()
+		return
+	}
+	if item == nil {
+		go func() {
+			logOnError(eqc.Tellf(teller, "You told me '%v', but I'm auctioning several items: %v.", tellMsg, a.itemNames()))
+			logOnError(eqc.Tellf(teller, "Please send me the item number followed by your bid (e.g. '2 150'), or the item number followed by 0 to cancel."))
+		}()
+		return
+	}
+	itemName := item.Name
+	cancelHint := "Send 0 to cancel."
+	if len(a.items) > 1 {
+		cancelHint = fmt.Sprintf("Send '%d 0' to cancel.", item.Index)
+	}
+	var bidValue float64
+	parsed := a.parseBid(teller, item, bidText)
+	switch parsed.Action {
+	case plugin.BidReject:
+		go func() {
+			if parsed.Reason != "" {
+				logOnError(eqc.Tell(teller, parsed.Reason))
 				return
-			case <-time.After(10 * time.Second):
-				break
 			}
-
-			logOnError(dc.Play(assets.BellTone()))
-			announce(eqc, dc,
-				[]interface{}{">> Bidding closed for ", itemLink, " <<"},
-				"No more bids for "+itemEscape+".")
+			logOnError(eqc.Tellf(teller, "You told me '%v', and I can't make any sense of that as a bid.", tellMsg))
+			logOnError(eqc.Tellf(teller, "Please send me your bid as a number (or 'all'), or 0 to cancel a previous bid."))
 		}()
-		auctionResult := <-resultChan
-		price, winners, displays, err := gp.SortBids(auctionResult.bids, 1)
+		return
+	case plugin.BidAllIn:
+		dkpTotal, err := gp.GetDKP(teller)
+		if err != nil || math.IsNaN(dkpTotal) || dkpTotal <= 0 {
+			if err != nil {
+				log.Println(err)
+			}
+			go func() {
+				logOnError(eqc.Tell(teller, "Sorry, I don't know how much DKP you have, so please bid a number instead."))
+			}()
+			return
+		}
+		bidValue = dkpTotal
+	case plugin.BidCancel:
+		bidValue = 0
+	default:
+		bidValue = parsed.Amount
+	}
+	if a.request.Open {
+		a.handleOpenBid(item, teller, bidValue)
+		return
+	}
+	if bidValue == 0 {
+		if _, ok := item.bids[teller]; ok {
+			delete(item.bids, teller)
+			go func() { logOnError(eqc.Tellf(teller, "Cancelled your bid on %v.", itemName)) }()
+		} else {
+			go func() { logOnError(eqc.Tellf(teller, "You haven't placed a bid on %v yet!", itemName)) }()
+		}
+		return
+	}
+	if !a.validateBid(item, teller, bidValue) {
+		return
+	}
+	prevBid, hadPrev := item.bids[teller]
+	highBid := true
+	for bidder, bid := range item.bids {
+		if bidder != teller && bid >= bidValue {
+			highBid = false
+		}
+	}
+	item.bids[teller] = bidValue
+	if highBid {
+		a.checkSnipe(item)
+	}
+	dkpTotal, err := gp.GetDKP(teller)
+	if err == nil && bidValue > dkpTotal {
+		go func() {
+			logOnError(eqc.Tellf(teller, "Entering your bid of %v on %v, even though you only have %v DKP.  %v",
+				bidValue, itemName, dkpTotal, cancelHint))
+		}()
+	} else {
 		if err != nil {
 			log.Println(err)
-		} else if len(winners) == 0 {
-			logOnError(eqc.Announce(">> Preliminary winner(s) of ", itemLink, ": no bids <<"))
+		}
+		if hadPrev {
 			go func() {
-				logOnError(dc.WriteComplex(&discordgo.MessageSend{
-					Embed: &discordgo.MessageEmbed{
-						Title:       "Bid end",
-						Description: fmt.Sprintf("%v: No bids", itemEscape),
-						Color:       0x007f00,
-					},
-				}))
+				logOnError(eqc.Tellf(teller, "Received your bid of %v on %v, replacing your previous bid of %v.  %v", bidValue, itemName, prevBid, cancelHint))
 			}()
 		} else {
-			for idx, winner := range winners {
-				winners[idx] = inicap(winner)
-			}
-			logOnError(eqc.Announce(">> Preliminary winner(s) of ", itemLink,
-				": "+strings.Join(winners, " "),
-				fmt.Sprintf(" for %v DKP. <<", price)))
 			go func() {
-				eb := &discordgo.MessageEmbed{
+				logOnError(eqc.Tellf(teller, "Received your bid of %v on %v.  %v", bidValue, itemName, cancelHint))
+			}()
+		}
+	}
+}
+
+// Describe the auction of one item to the rules plugin
+func (a *auction) pluginContext(item *auctionItem) *plugin.AuctionContext {
+	return &plugin.AuctionContext{
+		ItemName:  item.Name,
+		Count:     item.Count,
+		Zone:      a.zone,
+		Boss:      a.boss,
+		StartedBy: a.request.RequestedBy,
+		Start:     a.start,
+	}
+}
+
+// Turn the text of a tell into a bid, letting the rules plugin have the first say
+func (a *auction) parseBid(teller string, item *auctionItem, bidText string) *plugin.ParsedBid {
+	parsed, err := a.gp.ParseBid(teller, bidText, a.pluginContext(item))
+	if err != nil {
+		log.Println(err)
+	}
+	if parsed != nil {
+		return parsed
+	}
+	return parseBidText(bidText)
+}
+
+// Check a bid with the rules plugin, telling the bidder if it's rejected
+func (a *auction) validateBid(item *auctionItem, teller string, bidValue float64) bool {
+	eqc := a.eqc
+	errmsg, err := a.gp.ValidateBid(teller, bidValue, a.pluginContext(item))
+	if err != nil {
+		log.Println(err)
+		go func() { logOnError(eqc.Tellf(teller, "Sorry, I had a problem validating your bid.")) }()
+		return false
+	}
+	if errmsg != "" {
+		go func() { logOnError(eqc.Tell(teller, errmsg)) }()
+		return false
+	}
+	return true
+}
+
+// Process a bid in an open auction, where each bid has to beat the current high bid
+func (a *auction) handleOpenBid(item *auctionItem, teller string, bidValue float64) {
+	eqc, itemName := a.eqc, item.Name
+	if bidValue == 0 {
+		go func() { logOnError(eqc.Tell(teller, "Bids can't be withdrawn in an open auction.")) }()
+		return
+	}
+	highBidder, highBid := item.highBid()
+	if highBidder == teller {
+		go func() { logOnError(eqc.Tellf(teller, "You already have the high bid of %v on %v.", highBid, itemName)) }()
+		return
+	}
+	if highBidder != "" && (bidValue <= highBid || bidValue < highBid+a.schedule.OpenIncrement) {
+		minimum := highBid + a.schedule.OpenIncrement
+		go func() {
+			logOnError(eqc.Tellf(teller, "The high bid on %v is %v, so you need to bid at least %v.", itemName, highBid, minimum))
+		}()
+		return
+	}
+	if !a.validateBid(item, teller, bidValue) {
+		return
+	}
+	item.bids[teller] = bidValue
+	a.timeline.reset(a.schedule.OpenIdle)
+	go a.announceHighBid(item, teller, bidValue)
+
+	dkpTotal, err := a.gp.GetDKP(teller)
+	if err == nil && bidValue > dkpTotal {
+		go func() {
+			logOnError(eqc.Tellf(teller, "You have the high bid of %v on %v, even though you only have %v DKP.",
+				bidValue, itemName, dkpTotal))
+		}()
+	} else {
+		if err != nil {
+			log.Println(err)
+		}
+		go func() { logOnError(eqc.Tellf(teller, "You have the high bid of %v on %v.", bidValue, itemName)) }()
+	}
+}
+
+// Announce a new high bid in an open auction, unless it's been beaten already
+func (a *auction) announceHighBid(item *auctionItem, bidder string, bid float64) {
+	a.announceSync.Lock()
+	defer a.announceSync.Unlock()
+	a.sync.Lock()
+	_, highBid := item.highBid()
+	a.sync.Unlock()
+	if highBid > bid {
+		return
+	}
+	logOnError(a.dc.Writef("---- New high bid on %v: %v DKP from `%v`", item.dcText(false), bid, inicap(bidder)))
+	announce(a.eqc, a.dc,
+		append(append([]interface{}{">> New high bid on "}, item.eqText(false)...),
+			fmt.Sprintf(": %v DKP from %v.  Bidding closes after %v without a higher bid. <<",
+				bid, inicap(bidder), secondsText(a.schedule.OpenIdle))),
+		fmt.Sprintf("%v bids %v on %v.", inicap(bidder), bid, item.Escape))
+}
+
+// Work out who won an item, and what they pay
+func (a *auction) results(item *auctionItem) (price float64, winners []string, displays []plugin.BidDesc, err error) {
+	if !a.request.Open {
+		return a.gp.SortBids(item.bids, item.Count, a.pluginContext(item))
+	}
+	// In an open auction, the high bidder pays what they bid.
+	bidders := make([]string, 0, len(item.bids))
+	for bidder := range item.bids {
+		bidders = append(bidders, bidder)
+	}
+	sort.Slice(bidders, func(i, j int) bool { return item.bids[bidders[i]] > item.bids[bidders[j]] })
+	displays = make([]plugin.BidDesc, 0, len(bidders))
+	for _, bidder := range bidders {
+		displays = append(displays, plugin.BidDesc{BidderDesc: inicap(bidder), BidDesc: fmt.Sprintf("%v DKP", item.bids[bidder])})
+	}
+	if len(bidders) == 0 {
+		return 0, []string{}, displays, nil
+	}
+	return item.bids[bidders[0]], bidders[:1], displays, nil
+}
+
+// Save the outcome of one of the auction's items to the auction history
+func (a *auction) saveRecord(item *auctionItem, price float64, winners []string, displays []plugin.BidDesc) {
+	record := &storage.AuctionRecord{
+		ItemName:  item.Name,
+		Count:     item.Count,
+		StartedBy: strings.ToLower(a.request.RequestedBy),
+		Start:     a.start,
+		End:       time.Now(),
+		BidTexts:  item.bidTexts,
+		Bids:      make(map[string]float64),
+		Winners:   append([]string{}, winners...),
+		Price:     price,
+		Displays:  make([]storage.BidDisplay, 0, len(displays)),
+		Zone:      a.zone,
+		Boss:      a.boss,
+	}
+	for bidder, bid := range item.bids {
+		record.Bids[bidder] = bid
+	}
+	for _, display := range displays {
+		record.Displays = append(record.Displays, storage.BidDisplay{BidderDesc: display.BidderDesc, BidDesc: display.BidDesc})
+	}
+	err := storage.SaveAuctionRecord(record)
+	if err != nil {
+		log.Printf("Failed to save auction history: %v", err)
+		return
+	}
+	item.record = record
+}
+
+// Determine and announce the winners of one of the auction's items
+func (a *auction) settle(item *auctionItem, numbered bool) {
+	eqc, dc := a.eqc, a.dc
+	itemText := item.eqText(numbered)
+	price, winners, displays, err := a.results(item)
+	if err != nil {
+		log.Println(err)
+		return
+	}
+	a.saveRecord(item, price, winners, displays)
+	if len(winners) == 0 {
+		logOnError(eqc.Announce(append(append([]interface{}{">> Preliminary winner(s) of "}, itemText...), ": no bids <<")...))
+		go func() {
+			logOnError(dc.WriteComplex(&discordgo.MessageSend{
+				Embed: &discordgo.MessageEmbed{
 					Title:       "Bid end",
-					Description: fmt.Sprintf("%v: [%v DKP] `%v`", itemEscape, price, strings.Join(winners, "`, `")),
-					Fields:      make([]*discordgo.MessageEmbedField, 0),
+					Description: fmt.Sprintf("%v: No bids%v", item.dcText(numbered), item.dcInfo()),
 					Color:       0x007f00,
+				},
+			}))
+		}()
+	} else {
+		eachText := ""
+		if item.Count > 1 {
+			eachText = " each"
+		}
+		for idx, winner := range winners {
+			winners[idx] = inicap(winner)
+		}
+		logOnError(eqc.Announce(append(append([]interface{}{">> Preliminary winner(s) of "}, itemText...),
+			": "+strings.Join(winners, " "),
+			fmt.Sprintf(" for %v DKP%v. <<", price, eachText))...))
+		record := item.record
+		go func() {
+			eb := &discordgo.MessageEmbed{
+				Title:       "Bid end",
+				Description: fmt.Sprintf("%v: [%v DKP%v] `%v`%v", item.dcText(numbered), price, eachText, strings.Join(winners, "`, `"), item.dcInfo()),
+				Fields:      make([]*discordgo.MessageEmbedField, 0),
+				Footer:      &discordgo.MessageEmbedFooter{Text: "Officers: react with " + confirmEmoji + " or use !confirm to finalize"},
+				Color:       0x007f00,
+			}
+			for i := 0; i < 9 && i < len(displays); i++ {
+				eb.Fields = append(eb.Fields, &discordgo.MessageEmbedField{
+					Name:   "`" + displays[i].BidderDesc + "`",
+					Value:  displays[i].BidDesc,
+					Inline: true,
+				})
+			}
+			msg, err := dc.WriteComplex(&discordgo.MessageSend{Embed: eb})
+			if err != nil {
+				log.Println(err)
+			} else if record != nil {
+				trackResultMessage(dc, record.ID, msg)
+			}
+			for i := 9; i < len(displays); i += 9 {
+				eb = &discordgo.MessageEmbed{
+					Title:  "Bid end",
+					Fields: make([]*discordgo.MessageEmbedField, 0),
+					Color:  0x007f00,
 				}
-				for i := 0; i < 9 && i < len(displays); i++ {
+				for j := i; j < i+9 && j < len(displays); j++ {
 					eb.Fields = append(eb.Fields, &discordgo.MessageEmbedField{
-						Name:   "`" + displays[i].BidderDesc + "`",
-						Value:  displays[i].BidDesc,
+						Name:   "`" + displays[j].BidderDesc + "`",
+						Value:  displays[j].BidDesc,
 						Inline: true,
 					})
 				}
 				logOnError(dc.WriteComplex(&discordgo.MessageSend{Embed: eb}))
-				for i := 9; i < len(displays); i += 9 {
-					eb = &discordgo.MessageEmbed{
-						Title:  "Bid end",
-						Fields: make([]*discordgo.MessageEmbedField, 0),
-						Color:  0x007f00,
-					}
-					for j := i; j < i+9 && j < len(displays); j++ {
-						eb.Fields = append(eb.Fields, &discordgo.MessageEmbedField{
-							Name:   "`" + displays[j].BidderDesc + "`",
-							Value:  displays[j].BidDesc,
-							Inline: true,
-						})
-					}
-					logOnError(dc.WriteComplex(&discordgo.MessageSend{Embed: eb}))
-				}
-			}()
-		}
-		for _, toUpdate := range auctionResult.bidTexts {
-			go func(updateEntry bidEntry) {
-				logOnError(dc.Session.ChannelMessageEdit(
-					updateEntry.MsgEntry.ChannelID,
-					updateEntry.MsgEntry.ID,
-					fmt.Sprintf("`%v:` %v", inicap(updateEntry.Bidder), updateEntry.BidText)))
-			}(toUpdate)
-		}
+			}
+		}()
+	}
+}
+
+// Remember where an item's result was posted, so that officers can confirm it with a reaction
+func trackResultMessage(dc *discord.Client, recordID uint64, msg *discordgo.Message) {
+	_, err := storage.UpdateAuctionRecord(recordID, func(record *storage.AuctionRecord) error {
+		record.ChannelID = msg.ChannelID
+		record.MessageID = msg.ID
+		return nil
 	})
+	logOnError(err)
+	logOnError(dc.Session.MessageReactionAdd(msg.ChannelID, msg.ID, confirmEmoji))
 }
@@ -1,6 +1,9 @@
 package bot
 
-import "testing"
+import (
+	"github.com/gontikr99/bidbot2/controller/plugin"
+	"testing"
+)
 
 func Test_numre(t *testing.T) {
 	parts := numRE.FindStringSubmatch("15")
@@ -8,3 +11,66 @@ func Test_numre(t *testing.T) {
 		t.Fail()
 	}
 }
+
+func Test_parseItemCount(t *testing.T) {
+	if count, item := parseItemCount(" Cloak of Flames"); count != 1 || item != "Cloak of Flames" {
+		t.Fatalf("Expected 1x Cloak of Flames, got %vx %v", count, item)
+	}
+	if count, item := parseItemCount(" 3x Rune of Frost"); count != 3 || item != "Rune of Frost" {
+		t.Fatalf("Expected 3x Rune of Frost, got %vx %v", count, item)
+	}
+	if count, item := parseItemCount(" 0x Rune of Frost"); count != 1 || item != "0x Rune of Frost" {
+		t.Fatalf("Expected 1x 0x Rune of Frost, got %vx %v", count, item)
+	}
+}
+
+func Test_parseAuctionItems(t *testing.T) {
+	items := parseAuctionItems(" Cloak of Flames | 2x Sword of Runes |")
+	if len(items) != 2 {
+		t.Fatalf("Expected 2 items, got %v", items)
+	}
+	if items[0].ItemName != "Cloak of Flames" || items[0].Count != 1 {
+		t.Fatalf("Expected 1x Cloak of Flames, got %v", items[0])
+	}
+	if items[1].ItemName != "Sword of Runes" || items[1].Count != 2 {
+		t.Fatalf("Expected 2x Sword of Runes, got %v", items[1])
+	}
+}
+
+func Test_awardRE(t *testing.T) {
+	parts := awardRE.FindStringSubmatch("Cloak of Flames Alice 150")
+	if parts == nil || parts[1] != "Cloak of Flames" || parts[2] != "Alice" || parts[3] != "150" {
+		t.Fatalf("Failed to parse award, got %v", parts)
+	}
+	parts = reassignRE.FindStringSubmatch("Cloak of Flames Alice Bob")
+	if parts == nil || parts[1] != "Cloak of Flames" || parts[2] != "Alice" || parts[3] != "Bob" {
+		t.Fatalf("Failed to parse reassignment, got %v", parts)
+	}
+}
+
+func Test_parseAuctionMode(t *testing.T) {
+	if open, args := parseAuctionMode(" open Cloak of Flames"); !open || args != "Cloak of Flames" {
+		t.Fatalf("Expected open Cloak of Flames, got %v %v", open, args)
+	}
+	if open, args := parseAuctionMode(" Opener's Cloak"); open || args != " Opener's Cloak" {
+		t.Fatalf("Expected sealed Opener's Cloak, got %v %v", open, args)
+	}
+}
+
+func Test_parseBidText(t *testing.T) {
+	expect := func(text string, action plugin.BidAction, amount float64) {
+		parsed := parseBidText(text)
+		if parsed.Action != action || parsed.Amount != amount {
+			t.Fatalf("Expected '%v' to parse as %v %v, got %v", text, action, amount, parsed)
+		}
+	}
+	expect("150", plugin.BidAmount, 150)
+	expect("50 for my alt", plugin.BidAmount, 50)
+	expect("0", plugin.BidCancel, 0)
+	expect("Cancel please", plugin.BidCancel, 0)
+	expect("all", plugin.BidAllIn, 0)
+	expect("all in", plugin.BidAllIn, 0)
+	expect("MAX", plugin.BidAllIn, 0)
+	expect("allowance", plugin.BidReject, 0)
+	expect("what is this", plugin.BidReject, 0)
+}
@@ -0,0 +1,291 @@
+package bot
+
+import (
+	"errors"
+	"fmt"
+	"github.com/bwmarrin/discordgo"
+	"github.com/gontikr99/bidbot2/controller/discord"
+	"github.com/gontikr99/bidbot2/controller/everquest"
+	"github.com/gontikr99/bidbot2/controller/plugin"
+	"github.com/gontikr99/bidbot2/controller/storage"
+	"log"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Reaction an officer adds to a "Bid end" embed to confirm the result
+const confirmEmoji = "✅"
+
+var awardRE = regexp.MustCompile(`^(.+?)\s+([A-Za-z]+)\s+([0-9]+(?:\.[0-9]+)?)$`)
+var reassignRE = regexp.MustCompile(`^(.+?)\s+([A-Za-z]+)\s+([A-Za-z]+)$`)
+
+var errAlreadyFinal = errors.New("that auction has already been finalized")
+
+// Officer commands which turn preliminary auction results into final awards
+func RegisterAwardCommands(eqc *everquest.Client, dc *discord.Client, gp *plugin.GuildPlugin) {
+	eqc.RegisterCCCommand("!confirm", func(who string, args string) {
+		records, err := pendingToConfirm(strings.TrimSpace(args))
+		if err != nil {
+			log.Println(err)
+			logOnError(eqc.Tell(who, "Sorry, I couldn't look up the auction results."))
+			return
+		}
+		if len(records) == 0 {
+			logOnError(eqc.Tell(who, "There's nothing waiting to be confirmed."))
+			return
+		}
+		for _, pending := range records {
+			record, err := confirmAuction(pending.ID, strings.ToLower(who))
+			if err != nil {
+				logOnError(eqc.Tellf(who, "Couldn't confirm %v: %v", pending.ItemName, err))
+				continue
+			}
+			postAward(eqc, dc, gp, record, inicap(who))
+		}
+	})
+
+	eqc.RegisterCCCommand("!award", func(who string, args string) {
+		parts := awardRE.FindStringSubmatch(strings.TrimSpace(args))
+		if parts == nil {
+			logOnError(eqc.Tell(who, "Usage: !award <item> <character> <price>"))
+			return
+		}
+		itemName, charname := parts[1], strings.ToLower(parts[2])
+		price, _ := strconv.ParseFloat(parts[3], 64)
+		action := fmt.Sprintf("awarded to %v for %v DKP", inicap(charname), price)
+
+		latest, err := latestAuction(itemName)
+		if err != nil {
+			log.Println(err)
+			logOnError(eqc.Tell(who, "Sorry, I couldn't look up the auction results."))
+			return
+		}
+		var record *storage.AuctionRecord
+		if latest != nil {
+			// Changing the awards of the same record means the DKP charged for it is replaced too, rather than
+			// charged a second time.
+			record, err = storage.UpdateAuctionRecord(latest.ID, func(record *storage.AuctionRecord) error {
+				previous := record.CurrentAwards()
+				awards, err := changeAward(previous, record.Count, charname, price)
+				if err != nil {
+					return err
+				}
+				if record.Final {
+					record.Audited(strings.ToLower(who), action+", changed from "+describeAwards(previous))
+				} else {
+					record.Audited(strings.ToLower(who), action+" instead of "+describeAwards(previous))
+				}
+				record.Final = true
+				record.Awards = awards
+				return nil
+			})
+		} else {
+			// Nothing was auctioned under that name, so record the award by itself.
+			record = &storage.AuctionRecord{
+				ItemName:  itemName,
+				Count:     1,
+				StartedBy: strings.ToLower(who),
+				Start:     time.Now(),
+				End:       time.Now(),
+				Bids:      make(map[string]float64),
+				Winners:   []string{charname},
+				Price:     price,
+				Final:     true,
+				Awards:    []storage.Award{{Character: charname, Price: price}},
+			}
+			record.Audited(strings.ToLower(who), action+" without an auction")
+			err = storage.SaveAuctionRecord(record)
+		}
+		if err != nil {
+			log.Println(err)
+			logOnError(eqc.Tellf(who, "Couldn't award %v: %v", itemName, err))
+			return
+		}
+		postAward(eqc, dc, gp, record, inicap(who))
+	})
+
+	eqc.RegisterCCCommand("!reassign", func(who string, args string) {
+		parts := reassignRE.FindStringSubmatch(strings.TrimSpace(args))
+		if parts == nil {
+			logOnError(eqc.Tell(who, "Usage: !reassign <item> <from character> <to character>"))
+			return
+		}
+		itemName, from, to := parts[1], strings.ToLower(parts[2]), strings.ToLower(parts[3])
+		records, err := storage.AuctionsOfItem(itemName)
+		if err != nil {
+			log.Println(err)
+			logOnError(eqc.Tell(who, "Sorry, I couldn't look up the auction results."))
+			return
+		}
+		var found *storage.AuctionRecord
+		for i := range records {
+			if awardIndex(records[i].CurrentAwards(), from) >= 0 {
+				found = &records[i]
+				break
+			}
+		}
+		if found == nil {
+			logOnError(eqc.Tellf(who, "I don't know of %v being awarded %v.", inicap(from), itemName))
+			return
+		}
+		record, err := storage.UpdateAuctionRecord(found.ID, func(record *storage.AuctionRecord) error {
+			awards := record.CurrentAwards()
+			idx := awardIndex(awards, from)
+			if idx < 0 {
+				return fmt.Errorf("%v no longer holds that award", inicap(from))
+			}
+			awards[idx].Character = to
+			record.Final = true
+			record.Awards = awards
+			record.Audited(strings.ToLower(who), fmt.Sprintf("reassigned from %v to %v", inicap(from), inicap(to)))
+			return nil
+		})
+		if err != nil {
+			log.Println(err)
+			logOnError(eqc.Tellf(who, "Couldn't reassign %v: %v", itemName, err))
+			return
+		}
+		postAward(eqc, dc, gp, record, inicap(who))
+	})
+
+	dc.RegisterReactionHandler(confirmEmoji, func(mra *discordgo.MessageReactionAdd) {
+		pending, err := storage.AuctionByMessage(mra.MessageID)
+		if err != nil {
+			// Not one of our results
+			return
+		}
+		if !dc.IsAdmin(mra.GuildID, mra.UserID) {
+			return
+		}
+		who := mra.UserID
+		if mra.Member != nil && mra.Member.User != nil {
+			who = mra.Member.User.Username
+		} else if user, err := dc.Session.User(mra.UserID); err == nil {
+			who = user.Username
+		}
+		record, err := confirmAuction(pending.ID, who+" (Discord)")
+		if err == errAlreadyFinal {
+			return
+		} else if err != nil {
+			log.Printf("Failed to confirm auction: %v", err)
+			return
+		}
+		postAward(eqc, dc, gp, record, who)
+	})
+}
+
+// Finalize an auction with its preliminary winners
+func confirmAuction(id uint64, who string) (*storage.AuctionRecord, error) {
+	return storage.UpdateAuctionRecord(id, func(record *storage.AuctionRecord) error {
+		if record.Final {
+			return errAlreadyFinal
+		}
+		record.Final = true
+		record.Awards = record.PreliminaryAwards()
+		record.Audited(who, "confirmed "+describeAwards(record.Awards))
+		return nil
+	})
+}
+
+// The unconfirmed auctions that `!confirm` applies to: the latest one of the named item, or every item of
+// the most recently finished auction.
+func pendingToConfirm(itemName string) ([]storage.AuctionRecord, error) {
+	if itemName != "" {
+		record, err := latestPending(itemName)
+		if err != nil || record == nil {
+			return nil, err
+		}
+		return []storage.AuctionRecord{*record}, nil
+	}
+	pending, err := storage.PendingAuctions()
+	if err != nil || len(pending) == 0 {
+		return nil, err
+	}
+	result := make([]storage.AuctionRecord, 0, len(pending))
+	for _, record := range pending {
+		if record.Start.Equal(pending[0].Start) && record.StartedBy == pending[0].StartedBy {
+			result = append(result, record)
+		}
+	}
+	return result, nil
+}
+
+// The most recent unconfirmed auction of the named item, or nil if there isn't one
+func latestPending(itemName string) (*storage.AuctionRecord, error) {
+	records, err := storage.AuctionsOfItem(itemName)
+	if err != nil {
+		return nil, err
+	}
+	for i := range records {
+		if !records[i].Final {
+			return &records[i], nil
+		}
+	}
+	return nil, nil
+}
+
+// The most recent auction of the named item, whether or not it's been confirmed, or nil if there isn't one
+func latestAuction(itemName string) (*storage.AuctionRecord, error) {
+	records, err := storage.AuctionsOfItem(itemName)
+	if err != nil || len(records) == 0 {
+		return nil, err
+	}
+	return &records[0], nil
+}
+
+// The awards of an auction of `count` copies after giving one to `charname` for `price`.  A character who
+// already holds an award has its price changed; otherwise a copy nobody was awarded goes to them, or with a
+// single copy, the award is replaced.  Copies held by others are left alone, as !reassign is for moving those.
+func changeAward(awards []storage.Award, count int, charname string, price float64) ([]storage.Award, error) {
+	if idx := awardIndex(awards, charname); idx >= 0 {
+		awards[idx].Price = price
+		return awards, nil
+	}
+	if len(awards) < count {
+		return append(awards, storage.Award{Character: charname, Price: price}), nil
+	}
+	if len(awards) <= 1 {
+		return []storage.Award{{Character: charname, Price: price}}, nil
+	}
+	return nil, fmt.Errorf("every copy has been awarded (%v), use !reassign to give one to %v",
+		describeAwards(awards), inicap(charname))
+}
+
+func awardIndex(awards []storage.Award, charname string) int {
+	for idx, award := range awards {
+		if strings.ToLower(award.Character) == charname {
+			return idx
+		}
+	}
+	return -1
+}
+
+// Describe a set of awards, e.g. "Alice for 10 DKP, Bob for 10 DKP"
+func describeAwards(awards []storage.Award) string {
+	if len(awards) == 0 {
+		return "no award"
+	}
+	parts := make([]string, 0, len(awards))
+	for _, award := range awards {
+		parts = append(parts, fmt.Sprintf("%v for %v DKP", inicap(award.Character), award.Price))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// Charge the DKP for the final result of an auction, and announce it in guild and Discord
+func postAward(eqc *everquest.Client, dc *discord.Client, gp *plugin.GuildPlugin, record *storage.AuctionRecord, who string) {
+	recordAwardSpends(gp, record)
+	itemText := countPrefix(record.Count) + record.ItemName
+	logOnError(eqc.Announce(fmt.Sprintf(">> Final result for %v: %v. <<", itemText, describeAwards(record.Awards))))
+	logOnError(dc.WriteComplex(&discordgo.MessageSend{
+		Embed: &discordgo.MessageEmbed{
+			Title: "Award",
+			Description: fmt.Sprintf("`%v`: %v", strings.ReplaceAll(itemText, "`", "'"),
+				describeAwards(record.Awards)),
+			Footer: &discordgo.MessageEmbedFooter{Text: fmt.Sprintf("Auction #%d, finalized by %v", record.ID, who)},
+			Color:  0x007f00,
+		},
+	}))
+}
@@ -0,0 +1,28 @@
+package bot
+
+import (
+	"github.com/gontikr99/bidbot2/controller/storage"
+	"reflect"
+	"testing"
+)
+
+func Test_changeAward(t *testing.T) {
+	two := func() []storage.Award {
+		return []storage.Award{{Character: "alice", Price: 10}, {Character: "bob", Price: 10}}
+	}
+	awards, err := changeAward(two(), 2, "bob", 5)
+	if err != nil || !reflect.DeepEqual(awards, []storage.Award{{Character: "alice", Price: 10}, {Character: "bob", Price: 5}}) {
+		t.Fatalf("Expected only Bob's price to change, got %v, %v", awards, err)
+	}
+	awards, err = changeAward(two(), 3, "carol", 7)
+	if err != nil || !reflect.DeepEqual(awards, []storage.Award{{Character: "alice", Price: 10}, {Character: "bob", Price: 10}, {Character: "carol", Price: 7}}) {
+		t.Fatalf("Expected Carol to get the unawarded copy, got %v, %v", awards, err)
+	}
+	if awards, err = changeAward(two(), 2, "carol", 7); err == nil {
+		t.Fatalf("Expected awarding a third character two copies to be refused, got %v", awards)
+	}
+	awards, err = changeAward([]storage.Award{{Character: "alice", Price: 10}}, 1, "carol", 7)
+	if err != nil || !reflect.DeepEqual(awards, []storage.Award{{Character: "carol", Price: 7}}) {
+		t.Fatalf("Expected Carol to replace Alice, got %v, %v", awards, err)
+	}
+}
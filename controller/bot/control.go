@@ -0,0 +1,128 @@
+package bot
+
+import (
+	"github.com/gontikr99/bidbot2/controller/storage"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// C&C commands which change the auction that's currently running
+func (aq *auctionQueue) registerControlCommands() {
+	eqc := aq.eqc
+
+	// Find the running auction, or tell `who` that there isn't one
+	running := func(who string) *auction {
+		aq.sync.Lock()
+		a := aq.current
+		aq.sync.Unlock()
+		if a == nil {
+			logOnError(eqc.Tell(who, "There's no auction running right now."))
+			return nil
+		}
+		a.sync.Lock()
+		started := a.timeline != nil
+		a.sync.Unlock()
+		if !started {
+			logOnError(eqc.Tell(who, "The auction is still starting up, try again in a moment."))
+			return nil
+		}
+		return a
+	}
+
+	eqc.RegisterCCCommand("!cancel", func(who string, args string) {
+		a := running(who)
+		if a == nil {
+			return
+		}
+		if !a.timeline.stop() {
+			logOnError(eqc.Tell(who, "Bidding has already closed."))
+			return
+		}
+		a.cancel()
+		logOnError(a.dc.Writef("---- [%v] **Bid Cancelled**: %v", who, a.dcItemNames()))
+		announce(eqc, a.dc,
+			a.eqAnnouncement(">> Auction cancelled for ", ", any bids have been discarded. <<"),
+			"Auction of "+a.itemNames()+" cancelled.")
+	})
+
+	eqc.RegisterCCCommand("!extend", func(who string, args string) {
+		seconds, err := strconv.Atoi(strings.TrimSpace(args))
+		if err != nil || seconds <= 0 {
+			logOnError(eqc.Tell(who, "How many seconds did you want me to extend the auction by?"))
+			return
+		}
+		a := running(who)
+		if a == nil {
+			return
+		}
+		if !a.timeline.extendBy(time.Duration(seconds) * time.Second) {
+			logOnError(eqc.Tell(who, "Bidding has already closed."))
+			return
+		}
+		left := secondsText(a.timeline.remaining())
+		logOnError(a.dc.Writef("---- [%v] **Bid Extended**: %v, %v remain", who, a.dcItemNames(), left))
+		announce(eqc, a.dc,
+			a.eqAnnouncement(">> Bidding extended for ", ".  "+left+" remain. <<"),
+			"Bidding extended for "+a.itemNames()+".  "+left+" to go!")
+	})
+
+	eqc.RegisterCCCommand("!pause", func(who string, args string) {
+		a := running(who)
+		if a == nil {
+			return
+		}
+		if !a.timeline.pause() {
+			logOnError(eqc.Tell(who, "The auction is already paused, or bidding has closed."))
+			return
+		}
+		logOnError(a.dc.Writef("---- [%v] **Bid Paused**: %v", who, a.dcItemNames()))
+		announce(eqc, a.dc,
+			a.eqAnnouncement(">> Bidding paused for ", ".  Bids are still accepted while paused. <<"),
+			"Bidding paused for "+a.itemNames()+".")
+	})
+
+	eqc.RegisterCCCommand("!resume", func(who string, args string) {
+		a := running(who)
+		if a == nil {
+			return
+		}
+		if !a.timeline.resume() {
+			logOnError(eqc.Tell(who, "The auction isn't paused."))
+			return
+		}
+		left := secondsText(a.timeline.remaining())
+		logOnError(a.dc.Writef("---- [%v] **Bid Resumed**: %v, %v remain", who, a.dcItemNames(), left))
+		announce(eqc, a.dc,
+			a.eqAnnouncement(">> Bidding resumed for ", ".  "+left+" remain. <<"),
+			"Bidding resumed for "+a.itemNames()+".  "+left+" to go!")
+	})
+
+	eqc.RegisterCCCommand("!restart", func(who string, args string) {
+		a := running(who)
+		if a == nil {
+			return
+		}
+		if !a.restart() {
+			logOnError(eqc.Tell(who, "Bidding has already closed."))
+			return
+		}
+		logOnError(a.dc.Writef("---- [%v] **Bid Restarted**: %v", who, a.dcItemNames()))
+		logOnError(eqc.Announce(a.eqAnnouncement(">> Restarting the auction for ", ", all previous bids have been discarded. <<")...))
+	})
+}
+
+// Discard all bids and start the timeline over.  Returns false if bidding has already closed.
+func (a *auction) restart() bool {
+	a.sync.Lock()
+	defer a.sync.Unlock()
+	if !a.timeline.restart(a.openingDuration()) {
+		return false
+	}
+	a.start = time.Now()
+	for _, item := range a.items {
+		item.bids = make(map[string]float64)
+		item.bidTexts = make([]storage.BidText, 0)
+	}
+	return true
+}
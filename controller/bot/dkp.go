@@ -6,6 +6,7 @@ import (
 	"github.com/gontikr99/bidbot2/controller/discord"
 	"github.com/gontikr99/bidbot2/controller/everquest"
 	"github.com/gontikr99/bidbot2/controller/plugin"
+	"github.com/gontikr99/bidbot2/controller/storage"
 	"log"
 	"math"
 	"strings"
@@ -51,7 +52,7 @@ func RegisterDKPCommands(dc *discord.Client, eq *everquest.Client, gp *plugin.Gu
 			return
 		}
 
-		main, err := gp.GetMain(strings.TrimSpace(args))
+		main, err := gp.GetMain(args)
 		if err != nil {
 			dc.ReplyError(msg, "dkp", "An error occurred looking up the main of "+args+", sorry.")
 			log.Printf("Failed to lookup main of %v: %v", args, err)
@@ -67,9 +68,40 @@ func RegisterDKPCommands(dc *discord.Client, eq *everquest.Client, gp *plugin.Gu
 			dc.ReplyError(msg, "dkp", "An error occurred getting the DKP of "+main+", sorry.")
 			log.Printf("Failed to lookup DKP: %v", err)
 		} else if math.IsNaN(value) {
-			dc.ReplyWarn(msg, "dkp", "I don't know what "+main+"'s DKP total is.")
+			dc.ReplyWarn(msg, "dkp", "I don't know what "+main+"'s DKP total is."+
+				mainOverrideNotes(args, main)+recentLedger(main))
 		} else {
-			dc.ReplyOK(msg, "dkp", fmt.Sprintf("%v has %v DKP.", main, value))
+			dc.ReplyOK(msg, "dkp", fmt.Sprintf("%v has %v DKP.", main, value)+
+				mainOverrideNotes(args, main)+recentLedger(main))
 		}
 	})
 }
+
+// Number of ledger entries shown by Discord's !dkp
+const recentLedgerCount = 5
+
+// The ledger balance and latest ledger entries of a main, as lines to append to a DKP reply, or "" if the
+// ledger has nothing for them
+func recentLedger(main string) string {
+	entries, err := storage.LedgerEntriesOf(main)
+	if err != nil {
+		log.Printf("Failed to read DKP ledger: %v", err)
+		return ""
+	}
+	if len(entries) == 0 {
+		return ""
+	}
+	balance := 0.0
+	for _, entry := range entries {
+		balance += entry.Amount
+	}
+	sb := &strings.Builder{}
+	fmt.Fprintf(sb, "\nLedger balance: %v DKP", balance)
+	if len(entries) > recentLedgerCount {
+		entries = entries[len(entries)-recentLedgerCount:]
+	}
+	for idx := len(entries) - 1; idx >= 0; idx-- {
+		sb.WriteString("\n" + describeLedgerEntry(&entries[idx]))
+	}
+	return sb.String()
+}
@@ -0,0 +1,116 @@
+package bot
+
+import (
+	"fmt"
+	"github.com/bwmarrin/discordgo"
+	"github.com/gontikr99/bidbot2/controller/discord"
+	"github.com/gontikr99/bidbot2/controller/everquest"
+	"github.com/gontikr99/bidbot2/controller/storage"
+	"log"
+	"strings"
+)
+
+const notInRaidText = "Only raid members may bid, and I don't see you or your main in the raid.  If you're on standby, ask an officer to add you."
+
+// Work out who may bid from the raid dump taken at the start of the auction.  Anyone may bid if raid-only
+// bidding is turned off, or if the raid dump couldn't be read.
+func (a *auction) restrictToRaid(raidDump []byte) {
+	if !a.eqc.Config.RaidOnlyBids() {
+		return
+	}
+	members := everquest.ParseRaidDump(raidDump)
+	if len(members) == 0 {
+		log.Println("Couldn't read the raid, so anyone may bid")
+		logOnError(a.eqc.Tell(a.request.RequestedBy, "I couldn't read the raid, so anyone may bid."))
+		return
+	}
+	a.raidChars = make(map[string]bool)
+	a.raidMains = make(map[string]bool)
+	for _, member := range members {
+		a.raidChars[member.Name] = true
+		main, err := a.gp.GetMain(member.Name)
+		if err != nil {
+			log.Printf("Failed to lookup main of %v: %v", member.Name, err)
+			continue
+		}
+		if main != "" {
+			a.raidMains[main] = true
+		}
+	}
+}
+
+// Check whether a character may bid: they're in the raid, their main has a character in the raid, or
+// either of them is on the standby list.
+func (a *auction) mayBid(teller string) bool {
+	if a.raidChars == nil || a.raidChars[teller] || storage.IsStandby(teller) {
+		return true
+	}
+	main, err := a.gp.GetMain(teller)
+	if err != nil {
+		log.Printf("Failed to lookup main of %v: %v", teller, err)
+		return false
+	}
+	return main != "" && (a.raidChars[main] || a.raidMains[main] || storage.IsStandby(main))
+}
+
+// Officer commands to manage the characters who may bid from outside the raid
+func RegisterStandbyCommands(eqc *everquest.Client, dc *discord.Client) {
+	eqc.RegisterCCCommand("!standby", func(who string, args string) {
+		fields := strings.Fields(args)
+		switch {
+		case len(fields) == 0:
+			names, err := standbyNames()
+			if err != nil {
+				log.Printf("Failed to read standby list: %v", err)
+				logOnError(eqc.Tell(who, "An error occurred, sorry."))
+				return
+			}
+			logOnError(eqc.Tellf(who, "On standby: %v", names))
+		case len(fields) == 1:
+			if err := storage.AddStandby(fields[0]); err != nil {
+				log.Printf("Failed to add %v to standby: %v", fields[0], err)
+				logOnError(eqc.Tell(who, "An error occurred, sorry."))
+				return
+			}
+			logOnError(eqc.Tellf(who, "%v may now bid from outside the raid.", inicap(fields[0])))
+		case len(fields) == 2 && strings.EqualFold(fields[0], "remove"):
+			removed, err := storage.RemoveStandby(fields[1])
+			if err != nil {
+				log.Printf("Failed to remove %v from standby: %v", fields[1], err)
+				logOnError(eqc.Tell(who, "An error occurred, sorry."))
+			} else if !removed {
+				logOnError(eqc.Tellf(who, "%v wasn't on standby.", inicap(fields[1])))
+			} else {
+				logOnError(eqc.Tellf(who, "%v is no longer on standby.", inicap(fields[1])))
+			}
+		default:
+			logOnError(eqc.Tell(who, "Usage: !standby [<character> | remove <character>]"))
+		}
+	})
+
+	dc.RegisterDiscordCommand("!standby", func(msg *discordgo.MessageCreate, args string) {
+		dc.Fade(msg.Message)
+		names, err := standbyNames()
+		if err != nil {
+			dc.ReplyError(msg, "standby", "An error occurred reading the standby list, sorry.")
+			log.Printf("Failed to read standby list: %v", err)
+			return
+		}
+		dc.ReplyOK(msg, "standby", fmt.Sprintf("On standby: %v", names))
+	})
+}
+
+// The standby list as text, e.g. "Alice, Bob", or "nobody"
+func standbyNames() (string, error) {
+	names, err := storage.StandbyList()
+	if err != nil {
+		return "", err
+	}
+	if len(names) == 0 {
+		return "nobody", nil
+	}
+	for idx := range names {
+		names[idx] = inicap(names[idx])
+	}
+	return strings.Join(names, ", "), nil
+}
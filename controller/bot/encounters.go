@@ -0,0 +1,204 @@
+package bot
+
+import (
+	"fmt"
+	"github.com/bwmarrin/discordgo"
+	"github.com/gontikr99/bidbot2/controller/discord"
+	"github.com/gontikr99/bidbot2/controller/everquest"
+	"github.com/gontikr99/bidbot2/controller/everquest/events"
+	"github.com/gontikr99/bidbot2/controller/plugin"
+	"github.com/gontikr99/bidbot2/controller/storage"
+	"log"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// Number of kills remembered for the session
+	maxRecentKills = 50
+
+	// Characters' logs may disagree by a second or two about when something died, so kills of the same mob this
+	// close together in different logs are taken to be the same kill
+	killDedupeWindow = 5 * time.Second
+
+	// Auctions are tagged with a boss killed this recently
+	bossTagWindow = time.Hour
+
+	// Number of kills shown by !kills
+	killsShown = 10
+)
+
+// A mob's death, as seen in the log
+type kill struct {
+	Mob    string
+	Killer string
+	Zone   string
+	When   time.Time
+	Boss   bool // One of the configured bosses
+}
+
+// Keeps track of where the raid is and what it has killed this session, taking a raid dump and giving out
+// DKP whenever one of the configured bosses dies.
+type Encounters struct {
+	eqc *everquest.Client
+	dc  *discord.Client
+	gp  *plugin.GuildPlugin
+
+	sync     sync.Mutex
+	zones    map[string]string // Zone each character whose log we read last entered
+	lastZone string
+	kills    []kill
+}
+
+func StartEncounterTracking(eqc *everquest.Client, dc *discord.Client, gp *plugin.GuildPlugin) *Encounters {
+	enc := &Encounters{
+		eqc:   eqc,
+		dc:    dc,
+		gp:    gp,
+		zones: make(map[string]string),
+		kills: make([]kill, 0),
+	}
+	dc.RegisterDiscordCommand("!kills", func(msg *discordgo.MessageCreate, args string) {
+		dc.Fade(msg.Message)
+		kills := enc.RecentKills()
+		if len(kills) == 0 {
+			dc.ReplyOK(msg, "kills", "Nothing has been killed yet this session.")
+			return
+		}
+		if len(kills) > killsShown {
+			kills = kills[len(kills)-killsShown:]
+		}
+		lines := make([]string, 0, len(kills))
+		for idx := len(kills) - 1; idx >= 0; idx-- {
+			k := kills[idx]
+			line := fmt.Sprintf("%v: `%v` slain by %v", k.When.Format("15:04"), k.Mob, k.Killer)
+			if k.Zone != "" {
+				line += " in " + k.Zone
+			}
+			if k.Boss {
+				line += " (boss)"
+			}
+			lines = append(lines, line)
+		}
+		dc.ReplyOK(msg, "kills", strings.Join(lines, "\n"))
+	})
+	go enc.watch()
+	return enc
+}
+
+func (enc *Encounters) watch() {
+	encounters, done := enc.eqc.Subscribe(events.ZoneChangeType, events.SlainType)
+	defer done()
+	// The same kill shows up once for every character whose log we're reading.
+	seen := newLogDeduper(killDedupeWindow, maxRecentKills)
+	for {
+		select {
+		case <-enc.eqc.Context.Done():
+			return
+		case event := <-encounters:
+			if zoned, ok := event.(*events.ZoneChange); ok {
+				enc.sync.Lock()
+				enc.zones[strings.ToLower(zoned.Character)] = zoned.Zone
+				enc.lastZone = zoned.Zone
+				enc.sync.Unlock()
+				continue
+			}
+			slain := event.(*events.Slain)
+			when := slain.Time
+			if when.IsZero() {
+				when = time.Now()
+			}
+			if !seen.first(slain.Character, strings.ToLower(slain.Mob), when) {
+				continue
+			}
+			k := kill{
+				Mob:    slain.Mob,
+				Killer: slain.Killer,
+				Zone:   enc.Zone(),
+				When:   when,
+				Boss:   isBoss(enc.eqc.Config.Bosses(), slain.Mob),
+			}
+			enc.sync.Lock()
+			enc.kills = append(enc.kills, k)
+			if len(enc.kills) > maxRecentKills {
+				enc.kills = enc.kills[len(enc.kills)-maxRecentKills:]
+			}
+			enc.sync.Unlock()
+			if k.Boss {
+				go enc.bossKilled(k)
+			}
+		}
+	}
+}
+
+func isBoss(bosses []string, mob string) bool {
+	for _, boss := range bosses {
+		if strings.EqualFold(boss, mob) {
+			return true
+		}
+	}
+	return false
+}
+
+// The zone the bot's character is in, or the zone most recently entered by anyone whose log we read, or ""
+// if nobody has zoned this session
+func (enc *Encounters) Zone() string {
+	enc.sync.Lock()
+	defer enc.sync.Unlock()
+	if zone, ok := enc.zones[strings.ToLower(enc.eqc.BotName())]; ok {
+		return zone
+	}
+	return enc.lastZone
+}
+
+// The configured boss most recently killed within the last bossTagWindow, or "" if there isn't one
+func (enc *Encounters) RecentBoss() string {
+	enc.sync.Lock()
+	defer enc.sync.Unlock()
+	for idx := len(enc.kills) - 1; idx >= 0; idx-- {
+		k := enc.kills[idx]
+		if time.Since(k.When) > bossTagWindow {
+			break
+		}
+		if k.Boss {
+			return k.Mob
+		}
+	}
+	return ""
+}
+
+// The kills seen this session, oldest first
+func (enc *Encounters) RecentKills() []kill {
+	enc.sync.Lock()
+	defer enc.sync.Unlock()
+	return append([]kill{}, enc.kills...)
+}
+
+// Take a raid dump for attendance, and give the raid DKP for the kill
+func (enc *Encounters) bossKilled(k kill) {
+	eqc, dc := enc.eqc, enc.dc
+	raidDump, err := eqc.RaidDump()
+	if err != nil {
+		log.Printf("Failed to take raid dump after killing %v: %v", k.Mob, err)
+		return
+	}
+	if len(raidDump) == 0 {
+		log.Printf("Empty raid dump after killing %v, skipping", k.Mob)
+		return
+	}
+	logOnError(dc.Writef("[%d:%02d] %v has been slain!  Current raid members:", k.When.Hour(), k.When.Minute(), k.Mob))
+	logOnError(dc.Upload("raiddump.txt", raidDump))
+	recordRaidDump(k.When, raidDump)
+
+	amount := eqc.Config.BossKillDKP()
+	if amount <= 0 {
+		return
+	}
+	count, err := tickRaid(eqc, enc.gp, amount, storage.LedgerBoss, k.Mob, "boss kill")
+	if err != nil {
+		log.Printf("Failed to give DKP for killing %v: %v", k.Mob, err)
+		return
+	}
+	logOnError(dc.Writef("Gave %v DKP to %d raiders for killing %v", amount, count, k.Mob))
+}
@@ -0,0 +1,12 @@
+package bot
+
+import "testing"
+
+func Test_isBoss(t *testing.T) {
+	if !isBoss([]string{"Lady Vox", "lord nagafen"}, "Lord Nagafen") {
+		t.Fatal("Expected Lord Nagafen to be a boss")
+	}
+	if isBoss([]string{"Lady Vox", "lord nagafen"}, "a gnoll pup") {
+		t.Fatal("Didn't expect a gnoll pup to be a boss")
+	}
+}
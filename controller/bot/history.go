@@ -0,0 +1,79 @@
+package bot
+
+import (
+	"fmt"
+	"github.com/bwmarrin/discordgo"
+	"github.com/gontikr99/bidbot2/controller/discord"
+	"github.com/gontikr99/bidbot2/controller/storage"
+	"log"
+	"strings"
+)
+
+const historyReplyCount = 10
+
+// Describe a past auction in a single line
+func describeAuctionRecord(record *storage.AuctionRecord) string {
+	outcome := "no bids"
+	if record.Rolled && len(record.Winners) == 0 {
+		outcome = "no rolls"
+	}
+	if record.Final && len(record.Awards) != 0 {
+		outcome = "`" + describeAwards(record.Awards) + "`"
+	} else if !record.Final && len(record.Winners) != 0 {
+		outcome = "`" + describeAwards(record.PreliminaryAwards()) + "` (unconfirmed)"
+	}
+	return fmt.Sprintf("%v: %v`%v` -- %v", record.Start.Format("2006-01-02 15:04"), countPrefix(record.Count),
+		strings.ReplaceAll(record.ItemName, "`", "'"), outcome+rolledSuffix(record)+placeSuffix(record))
+}
+
+// Where an auction was held, e.g. " (Plane of Sky, Keeper of Souls)", or "" if that isn't known
+func placeSuffix(record *storage.AuctionRecord) string {
+	places := make([]string, 0, 2)
+	if record.Zone != "" {
+		places = append(places, record.Zone)
+	}
+	if record.Boss != "" {
+		places = append(places, record.Boss)
+	}
+	if len(places) == 0 {
+		return ""
+	}
+	return " (" + strings.Join(places, ", ") + ")"
+}
+
+func RegisterHistoryCommands(dc *discord.Client) {
+	dc.RegisterDiscordCommand("!history", func(msg *discordgo.MessageCreate, args string) {
+		dc.Fade(msg.Message)
+		args = strings.TrimSpace(args)
+		if args == "" {
+			dc.ReplyError(msg, "history", "Which item or character did you want the history of?")
+			return
+		}
+
+		records, err := storage.AuctionsOfItem(args)
+		if err == nil && len(records) == 0 {
+			records, err = storage.AuctionsWonBy(args)
+		}
+		if err != nil {
+			dc.ReplyError(msg, "history", "An error occurred looking up auction history, sorry.")
+			log.Printf("Failed to look up auction history: %v", err)
+			return
+		}
+		if len(records) == 0 {
+			dc.ReplyWarn(msg, "history", "I don't remember any auctions of or won by "+args+".")
+			return
+		}
+		lines := make([]string, 0, historyReplyCount)
+		for i := 0; i < historyReplyCount && i < len(records); i++ {
+			lines = append(lines, describeAuctionRecord(&records[i]))
+		}
+		dc.ReplyOK(msg, "history", strings.Join(lines, "\n"))
+	})
+}
+
+func rolledSuffix(record *storage.AuctionRecord) string {
+	if record.Rolled {
+		return " (roll-off)"
+	}
+	return ""
+}
@@ -0,0 +1,173 @@
+package bot
+
+import (
+	"fmt"
+	"github.com/gontikr99/bidbot2/controller/everquest"
+	"github.com/gontikr99/bidbot2/controller/plugin"
+	"github.com/gontikr99/bidbot2/controller/storage"
+	"log"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var tickRE = regexp.MustCompile(`^([0-9]+(?:\.[0-9]+)?)(?:\s+(.*))?$`)
+var adjustRE = regexp.MustCompile(`^([A-Za-z]+)\s+([-+]?[0-9]+(?:\.[0-9]+)?)(?:\s+(.*))?$`)
+
+// The main whose DKP a character's earnings and spending go to, or the character itself if its main isn't known
+func ledgerMain(gp *plugin.GuildPlugin, charname string) string {
+	charname = strings.ToLower(charname)
+	main, err := gp.GetMain(charname)
+	if err != nil {
+		log.Printf("Failed to lookup main of %v: %v", charname, err)
+		return charname
+	}
+	if main == "" {
+		return charname
+	}
+	return main
+}
+
+// Record the DKP spent on an auction's final awards, replacing whatever was recorded for it before
+func recordAwardSpends(gp *plugin.GuildPlugin, record *storage.AuctionRecord) {
+	by := ""
+	if len(record.Audit) != 0 {
+		by = record.Audit[len(record.Audit)-1].Who
+	}
+	entries := make([]storage.LedgerEntry, 0, len(record.Awards))
+	for _, award := range record.Awards {
+		if award.Price == 0 {
+			continue
+		}
+		entries = append(entries, storage.LedgerEntry{
+			When:      record.End,
+			Character: ledgerMain(gp, award.Character),
+			Amount:    -award.Price,
+			Reason:    fmt.Sprintf("%v (%v)", record.ItemName, inicap(award.Character)),
+			By:        by,
+		})
+	}
+	err := storage.SetAuctionSpends(record.ID, entries)
+	if err != nil {
+		log.Printf("Failed to record DKP spent on %v: %v", record.ItemName, err)
+	}
+}
+
+// The mains of everyone in the raid and on standby, in alphabetical order
+func raidMains(eqc *everquest.Client, gp *plugin.GuildPlugin) ([]string, error) {
+	members, err := eqc.RaidMembers()
+	if err != nil {
+		return nil, err
+	}
+	standby, err := storage.StandbyList()
+	if err != nil {
+		return nil, err
+	}
+	names := standby
+	for name := range members {
+		names = append(names, name)
+	}
+	return mainsOf(gp, names), nil
+}
+
+// The distinct mains of a set of characters, in alphabetical order
+func mainsOf(gp *plugin.GuildPlugin, names []string) []string {
+	mains := make(map[string]bool)
+	for _, name := range names {
+		mains[ledgerMain(gp, name)] = true
+	}
+	result := make([]string, 0, len(mains))
+	for main := range mains {
+		result = append(result, main)
+	}
+	sort.Strings(result)
+	return result
+}
+
+// Give everyone in the raid (and on standby) DKP, once per main.  Returns the number of mains credited.
+func tickRaid(eqc *everquest.Client, gp *plugin.GuildPlugin, amount float64, kind string, reason string, by string) (int, error) {
+	mains, err := raidMains(eqc, gp)
+	if err != nil {
+		return 0, err
+	}
+	if len(mains) == 0 {
+		return 0, fmt.Errorf("nobody is in the raid")
+	}
+	now := time.Now()
+	entries := make([]storage.LedgerEntry, 0, len(mains))
+	for _, main := range mains {
+		entries = append(entries, storage.LedgerEntry{
+			When:      now,
+			Character: main,
+			Amount:    amount,
+			Kind:      kind,
+			Reason:    reason,
+			By:        by,
+		})
+	}
+	return len(mains), storage.AddLedgerEntries(entries)
+}
+
+// Describe a ledger entry, e.g. "2020-11-03 +10 (tick: Plane of Sky)"
+func describeLedgerEntry(entry *storage.LedgerEntry) string {
+	text := fmt.Sprintf("%v %+g (%v", entry.When.Format("2006-01-02"), entry.Amount, entry.Kind)
+	if entry.Reason != "" {
+		text += ": " + entry.Reason
+	}
+	return text + ")"
+}
+
+// Officer commands which add to the DKP ledger
+func RegisterLedgerCommands(eqc *everquest.Client, gp *plugin.GuildPlugin) {
+	eqc.RegisterCCCommand("!tick", func(who string, args string) {
+		parts := tickRE.FindStringSubmatch(strings.TrimSpace(args))
+		if parts == nil {
+			logOnError(eqc.Tell(who, "Usage: !tick <amount> [reason]"))
+			return
+		}
+		amount, _ := strconv.ParseFloat(parts[1], 64)
+		reason := strings.TrimSpace(parts[2])
+		if reason == "" {
+			reason = "attendance"
+		}
+		count, err := tickRaid(eqc, gp, amount, storage.LedgerTick, reason, strings.ToLower(who))
+		if err != nil {
+			log.Printf("Failed to tick DKP: %v", err)
+			logOnError(eqc.Tellf(who, "Couldn't give out DKP: %v", err))
+			return
+		}
+		logOnError(eqc.Tellf(who, "Gave %v DKP to %d raiders for %v.", amount, count, reason))
+	})
+
+	eqc.RegisterCCCommand("!adjust", func(who string, args string) {
+		parts := adjustRE.FindStringSubmatch(strings.TrimSpace(args))
+		if parts == nil {
+			logOnError(eqc.Tell(who, "Usage: !adjust <character> <amount> [reason]"))
+			return
+		}
+		amount, _ := strconv.ParseFloat(parts[2], 64)
+		main := ledgerMain(gp, parts[1])
+		err := storage.AddLedgerEntries([]storage.LedgerEntry{{
+			When:      time.Now(),
+			Character: main,
+			Amount:    amount,
+			Kind:      storage.LedgerAdjust,
+			Reason:    strings.TrimSpace(parts[3]),
+			By:        strings.ToLower(who),
+		}})
+		if err != nil {
+			log.Printf("Failed to adjust DKP: %v", err)
+			logOnError(eqc.Tell(who, "An error occurred, sorry."))
+			return
+		}
+		balance, err := storage.LedgerBalance(main)
+		if err != nil {
+			log.Printf("Failed to read DKP ledger: %v", err)
+			logOnError(eqc.Tellf(who, "Adjusted %v by %v DKP.", inicap(main), amount))
+			return
+		}
+		logOnError(eqc.Tellf(who, "Adjusted %v by %v DKP, and they now have %v DKP.", inicap(main), amount, balance))
+	})
+}
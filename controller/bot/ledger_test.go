@@ -0,0 +1,36 @@
+package bot
+
+import (
+	"github.com/gontikr99/bidbot2/controller/storage"
+	"testing"
+	"time"
+)
+
+func Test_adjustRE(t *testing.T) {
+	parts := adjustRE.FindStringSubmatch("Alice -25.5 late to raid")
+	if parts == nil || parts[1] != "Alice" || parts[2] != "-25.5" || parts[3] != "late to raid" {
+		t.Fatalf("Failed to parse adjustment, got %v", parts)
+	}
+	if parts = tickRE.FindStringSubmatch("10"); parts == nil || parts[1] != "10" || parts[2] != "" {
+		t.Fatalf("Failed to parse tick, got %v", parts)
+	}
+	if parts = tickRE.FindStringSubmatch("-10"); parts != nil {
+		t.Fatalf("Ticks can't be negative, got %v", parts)
+	}
+}
+
+func Test_describeLedgerEntry(t *testing.T) {
+	entry := &storage.LedgerEntry{
+		When:   time.Date(2020, 11, 3, 21, 0, 0, 0, time.Local),
+		Amount: 10,
+		Kind:   storage.LedgerTick,
+		Reason: "Plane of Sky",
+	}
+	if text := describeLedgerEntry(entry); text != "2020-11-03 +10 (tick: Plane of Sky)" {
+		t.Fatalf("Unexpected description: %v", text)
+	}
+	entry.Amount, entry.Kind, entry.Reason = -150, storage.LedgerAward, ""
+	if text := describeLedgerEntry(entry); text != "2020-11-03 -150 (award)" {
+		t.Fatalf("Unexpected description: %v", text)
+	}
+}
@@ -0,0 +1,55 @@
+package bot
+
+import (
+	"strings"
+	"time"
+)
+
+// Recognizes the same line showing up in several characters' logs.  Each log is counted separately, so
+// something which really happens twice, and so is logged twice, still counts twice.  Logs don't always agree
+// to the second, so lines logged within `window` of each other are taken to be the same.
+type logDeduper struct {
+	window time.Duration
+	limit  int
+	seen   []*logSighting // Oldest first
+}
+
+type logSighting struct {
+	key    string
+	when   time.Time
+	counts map[string]int // How many times the line has been seen, by the character whose log it's in
+}
+
+// A deduper remembering the latest `limit` distinct lines
+func newLogDeduper(window time.Duration, limit int) *logDeduper {
+	return &logDeduper{window: window, limit: limit}
+}
+
+// Note that `key` was logged at `when` by `character`, returning false if another character's log has already
+// accounted for it
+func (d *logDeduper) first(character string, key string, when time.Time) bool {
+	var sighting *logSighting
+	for idx := len(d.seen) - 1; idx >= 0; idx-- {
+		s := d.seen[idx]
+		if s.key == key && s.when.Sub(when) <= d.window && when.Sub(s.when) <= d.window {
+			sighting = s
+			break
+		}
+	}
+	if sighting == nil {
+		sighting = &logSighting{key: key, when: when, counts: make(map[string]int)}
+		d.seen = append(d.seen, sighting)
+		if len(d.seen) > d.limit {
+			d.seen = d.seen[len(d.seen)-d.limit:]
+		}
+	}
+	character = strings.ToLower(character)
+	sighting.counts[character]++
+	count := sighting.counts[character]
+	for other, otherCount := range sighting.counts {
+		if other != character && otherCount >= count {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,77 @@
+package bot
+
+import (
+	"testing"
+	"time"
+)
+
+func Test_logDeduper(t *testing.T) {
+	type sighting struct {
+		character string
+		key       string
+		second    int
+		want      bool
+	}
+	tests := []struct {
+		name      string
+		window    time.Duration
+		sightings []sighting
+	}{
+		{"same line in two logs", 0, []sighting{
+			{"Alice", "loot", 0, true},
+			{"Bob", "loot", 0, false},
+		}},
+		{"twice in one log", 0, []sighting{
+			{"Alice", "loot", 0, true},
+			{"Alice", "loot", 0, true},
+		}},
+		{"twice in two logs", 0, []sighting{
+			{"Alice", "loot", 0, true},
+			{"Bob", "loot", 0, false},
+			{"Bob", "loot", 0, true},
+			{"Alice", "loot", 0, false},
+		}},
+		{"different lines", 0, []sighting{
+			{"Alice", "loot", 0, true},
+			{"Bob", "other loot", 0, true},
+		}},
+		{"a second apart without a window", 0, []sighting{
+			{"Alice", "kill", 0, true},
+			{"Bob", "kill", 1, true},
+		}},
+		{"a second apart within the window", 5 * time.Second, []sighting{
+			{"Alice", "kill", 1, true},
+			{"Bob", "kill", 0, false},
+		}},
+		{"outside the window", 5 * time.Second, []sighting{
+			{"Alice", "kill", 0, true},
+			{"Bob", "kill", 10, true},
+		}},
+	}
+	start := time.Date(2020, 11, 3, 21, 0, 0, 0, time.Local)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := newLogDeduper(tt.window, 10)
+			for idx, s := range tt.sightings {
+				when := start.Add(time.Duration(s.second) * time.Second)
+				if got := d.first(s.character, s.key, when); got != s.want {
+					t.Errorf("sighting %d: first(%v, %v) = %v, want %v", idx, s.character, s.key, got, s.want)
+				}
+			}
+		})
+	}
+}
+
+func Test_logDeduperEviction(t *testing.T) {
+	d := newLogDeduper(0, 2)
+	when := time.Date(2020, 11, 3, 21, 0, 0, 0, time.Local)
+	d.first("Alice", "first", when)
+	d.first("Alice", "second", when)
+	d.first("Alice", "third", when)
+	if d.first("Bob", "third", when) {
+		t.Fatal("Expected the latest line to be remembered")
+	}
+	if !d.first("Bob", "first", when) {
+		t.Fatal("Expected the oldest line to have been forgotten")
+	}
+}
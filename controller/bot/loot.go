@@ -0,0 +1,207 @@
+package bot
+
+import (
+	"errors"
+	"fmt"
+	"github.com/bwmarrin/discordgo"
+	"github.com/gontikr99/bidbot2/controller/everquest/events"
+	"github.com/gontikr99/bidbot2/controller/plugin"
+	"github.com/gontikr99/bidbot2/controller/storage"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Reaction an officer adds to a "Loot" post to auction the item
+const auctionLootEmoji = "🔨"
+
+var errLootHandled = errors.New("that item has already been auctioned or dismissed")
+
+// How many looted lines to remember, to recognize the same loot showing up in several characters' logs
+const lootSeenLimit = 1000
+
+// Watch the log for items picked up by the configured looters, and auction them or offer them up for auction
+func (aq *auctionQueue) watchLoot() {
+	loots, done := aq.eqc.Subscribe(events.LootType)
+	defer done()
+	seen := newLogDeduper(0, lootSeenLimit)
+	for {
+		select {
+		case <-aq.eqc.Context.Done():
+			return
+		case event := <-loots:
+			loot := event.(*events.Loot)
+			looter := strings.ToLower(loot.Looter)
+			if !isLooter(aq.eqc.Config.Looters(), looter) {
+				continue
+			}
+			// The same loot shows up once for every character whose log we're reading.
+			if !seen.first(loot.Character, loot.Timestamp+" "+looter+" "+loot.ItemName, loot.Time) {
+				continue
+			}
+			go aq.handleLoot(looter, loot.ItemName, loot.Count)
+		}
+	}
+}
+
+func isLooter(looters []string, charname string) bool {
+	for _, looter := range looters {
+		if strings.EqualFold(looter, charname) {
+			return true
+		}
+	}
+	return false
+}
+
+// Decide what to do with a looted item, via the plugin's shouldauction hook
+func (aq *auctionQueue) handleLoot(looter string, itemName string, count int) {
+	action, err := aq.gp.ShouldAuction(itemName, looter)
+	if err != nil {
+		log.Printf("shouldauction failed for %v: %v", itemName, err)
+		action = plugin.LootOffer
+	}
+	if action == plugin.LootIgnore {
+		return
+	}
+	record := &storage.LootRecord{
+		Looted:   time.Now(),
+		Looter:   looter,
+		ItemName: itemName,
+		Count:    count,
+		Handled:  action == plugin.LootAuction,
+	}
+	err = storage.SaveLootRecord(record)
+	if err != nil {
+		log.Printf("Failed to record loot: %v", err)
+	}
+	if action == plugin.LootAuction {
+		aq.auctionLoot(record, "")
+		return
+	}
+
+	itemText := strings.ReplaceAll(countPrefix(count)+itemName, "`", "'")
+	msg, err := aq.dc.WriteComplex(&discordgo.MessageSend{
+		Embed: &discordgo.MessageEmbed{
+			Title:       "Loot",
+			Description: fmt.Sprintf("`%v` looted `%v`", inicap(looter), itemText),
+			Footer:      &discordgo.MessageEmbedFooter{Text: "Officers: react with " + auctionLootEmoji + " or use !loot to auction it"},
+			Color:       0x007f00,
+		},
+	})
+	if err != nil {
+		log.Println(err)
+		return
+	}
+	if record.ID == 0 {
+		return
+	}
+	_, err = storage.UpdateLootRecord(record.ID, func(record *storage.LootRecord) error {
+		record.MessageID = msg.ID
+		return nil
+	})
+	if err != nil {
+		log.Printf("Failed to record loot message: %v", err)
+		return
+	}
+	logOnError(aq.dc.Session.MessageReactionAdd(msg.ChannelID, msg.ID, auctionLootEmoji))
+}
+
+// Queue an auction of looted items.  `who` is the officer who asked for it, or empty if the plugin did.
+func (aq *auctionQueue) auctionLoot(record *storage.LootRecord, who string) {
+	qa := &storage.QueuedAuction{
+		Items:       []storage.QueuedItem{{ItemName: record.ItemName, Count: record.Count}},
+		RequestedBy: record.Looter,
+		Queued:      time.Now(),
+	}
+	err := aq.enqueue(qa)
+	if err != nil {
+		log.Printf("Failed to queue auction of loot: %v", err)
+		return
+	}
+	if who == "" {
+		logOnError(aq.dc.Writef("Queued auction of `%v`, looted by %v", strings.ReplaceAll(queuedItemNames(qa), "`", "'"), inicap(record.Looter)))
+	} else {
+		logOnError(aq.dc.Writef("[%v] Queued auction of `%v`, looted by %v", who, strings.ReplaceAll(queuedItemNames(qa), "`", "'"), inicap(record.Looter)))
+	}
+}
+
+// Mark loot as handled, so it's neither offered nor auctioned again
+func markLootHandled(id uint64) (*storage.LootRecord, error) {
+	return storage.UpdateLootRecord(id, func(record *storage.LootRecord) error {
+		if record.Handled {
+			return errLootHandled
+		}
+		record.Handled = true
+		return nil
+	})
+}
+
+// Officer commands to auction or dismiss loot which hasn't been auctioned yet
+func (aq *auctionQueue) registerLootCommands() {
+	eqc, dc := aq.eqc, aq.dc
+	eqc.RegisterCCCommand("!loot", func(who string, args string) {
+		loot, err := storage.UnhandledLoot()
+		if err != nil {
+			log.Println(err)
+			logOnError(eqc.Tell(who, "Sorry, I couldn't read the loot list."))
+			return
+		}
+		args = strings.ToLower(strings.TrimSpace(args))
+		switch {
+		case args == "":
+			if len(loot) == 0 {
+				logOnError(eqc.Tell(who, "There's no loot waiting to be auctioned."))
+			}
+			for idx, record := range loot {
+				logOnError(eqc.Tellf(who, "%d: %v%v (looted by %v)", idx+1, countPrefix(record.Count), record.ItemName, inicap(record.Looter)))
+			}
+		case args == "clear":
+			for _, record := range loot {
+				_, err = markLootHandled(record.ID)
+				if err != nil && err != errLootHandled {
+					log.Printf("Failed to dismiss loot: %v", err)
+				}
+			}
+			logOnError(eqc.Tell(who, "Cleared the loot list."))
+		default:
+			position, err := strconv.Atoi(args)
+			if err != nil || position < 1 || position > len(loot) {
+				logOnError(eqc.Tell(who, "Usage: !loot [<number> | clear]"))
+				return
+			}
+			record, err := markLootHandled(loot[position-1].ID)
+			if err != nil {
+				logOnError(eqc.Tellf(who, "Couldn't auction %v: %v", loot[position-1].ItemName, err))
+				return
+			}
+			aq.auctionLoot(record, inicap(who))
+			logOnError(eqc.Tellf(who, "Queued auction of %v", record.ItemName))
+		}
+	})
+
+	dc.RegisterReactionHandler(auctionLootEmoji, func(mra *discordgo.MessageReactionAdd) {
+		pending, err := storage.LootByMessage(mra.MessageID)
+		if err != nil {
+			// Not one of our loot posts
+			return
+		}
+		if !dc.IsAdmin(mra.GuildID, mra.UserID) {
+			return
+		}
+		who := mra.UserID
+		if mra.Member != nil && mra.Member.User != nil {
+			who = mra.Member.User.Username
+		} else if user, err := dc.Session.User(mra.UserID); err == nil {
+			who = user.Username
+		}
+		record, err := markLootHandled(pending.ID)
+		if err == errLootHandled {
+			return
+		} else if err != nil {
+			log.Printf("Failed to auction loot: %v", err)
+			return
+		}
+		aq.auctionLoot(record, who)
+	})
+}
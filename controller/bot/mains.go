@@ -0,0 +1,91 @@
+package bot
+
+import (
+	"fmt"
+	"github.com/gontikr99/bidbot2/controller/everquest"
+	"github.com/gontikr99/bidbot2/controller/storage"
+	"log"
+	"strings"
+)
+
+// Register the Command and Control commands maintaining alt to main overrides, which take precedence over
+// the guild notes the plugin's getmain works from.
+func RegisterMainCommands(eqc *everquest.Client) {
+	eqc.RegisterCCCommand("!setmain", func(who string, args string) {
+		fields := strings.Fields(args)
+		if len(fields) != 2 {
+			logOnError(eqc.Tell(who, "Usage: !setmain <alt> <main>"))
+			return
+		}
+		if err := storage.SetMainOverride(fields[0], fields[1], who); err != nil {
+			log.Printf("Failed to set main of %v: %v", fields[0], err)
+			logOnError(eqc.Tell(who, "An error occurred, sorry."))
+			return
+		}
+		logOnError(eqc.Tellf(who, "%v will now be charged to %v.", inicap(fields[0]), inicap(fields[1])))
+	})
+
+	eqc.RegisterCCCommand("!clearmain", func(who string, args string) {
+		fields := strings.Fields(args)
+		if len(fields) != 1 {
+			logOnError(eqc.Tell(who, "Usage: !clearmain <alt>"))
+			return
+		}
+		cleared, err := storage.ClearMainOverride(fields[0])
+		if err != nil {
+			log.Printf("Failed to clear main of %v: %v", fields[0], err)
+			logOnError(eqc.Tell(who, "An error occurred, sorry."))
+		} else if !cleared {
+			logOnError(eqc.Tellf(who, "%v had no main set.", inicap(fields[0])))
+		} else {
+			logOnError(eqc.Tellf(who, "%v's main will come from the guild notes again.", inicap(fields[0])))
+		}
+	})
+
+	eqc.RegisterCCCommand("!mains", func(who string, args string) {
+		fields := strings.Fields(args)
+		if len(fields) != 1 {
+			logOnError(eqc.Tell(who, "Usage: !mains <main>"))
+			return
+		}
+		alts, err := storage.OverriddenAltsOf(fields[0])
+		if err != nil {
+			log.Printf("Failed to read alts of %v: %v", fields[0], err)
+			logOnError(eqc.Tell(who, "An error occurred, sorry."))
+			return
+		}
+		logOnError(eqc.Tellf(who, "Set as alts of %v: %v", inicap(fields[0]), overriddenAltNames(alts)))
+	})
+}
+
+// Names of the alts in a list of overrides as text, e.g. "Alice, Bob", or "nobody"
+func overriddenAltNames(overrides []storage.MainOverride) string {
+	if len(overrides) == 0 {
+		return "nobody"
+	}
+	names := make([]string, len(overrides))
+	for idx, override := range overrides {
+		names[idx] = inicap(override.Alt)
+	}
+	return strings.Join(names, ", ")
+}
+
+// Lines to append to a DKP reply describing the overrides involved in charging `charname` to `main`,
+// or "" if there are none
+func mainOverrideNotes(charname string, main string) string {
+	sb := &strings.Builder{}
+	override, err := storage.MainOverrideOf(charname)
+	if err != nil {
+		log.Printf("Failed to read main override of %v: %v", charname, err)
+	} else if override != nil {
+		fmt.Fprintf(sb, "\n%v's main was set to %v by %v with !setmain.",
+			inicap(override.Alt), inicap(override.Main), inicap(override.SetBy))
+	}
+	alts, err := storage.OverriddenAltsOf(main)
+	if err != nil {
+		log.Printf("Failed to read alts of %v: %v", main, err)
+	} else if len(alts) != 0 {
+		fmt.Fprintf(sb, "\nSet as alts of %v: %v", inicap(main), overriddenAltNames(alts))
+	}
+	return sb.String()
+}
@@ -7,7 +7,7 @@ import (
 	"time"
 )
 
-func StartPeriodicRaidDumps(eqc *everquest.Client, dc *discord.Client) {
+func StartPeriodicRaidDumps(eqc *everquest.Client, dc *discord.Client, policies *PolicyScheduler) {
 	go func() {
 		lastDump := time.Time{}
 		for {
@@ -35,6 +35,8 @@ func StartPeriodicRaidDumps(eqc *everquest.Client, dc *discord.Client) {
 			}
 			logOnError(dc.Writef("[%d:%02d] Current raid members:", nowTime.Hour(), nowTime.Minute()))
 			logOnError(dc.Upload("raiddump.txt", raidDump))
+			recordRaidDump(nowTime, raidDump)
+			policies.raidDumped(nowTime, raidDump)
 		}
 	}()
 }
@@ -0,0 +1,301 @@
+package bot
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/bwmarrin/discordgo"
+	"github.com/gontikr99/bidbot2/controller/discord"
+	"github.com/gontikr99/bidbot2/controller/everquest"
+	"github.com/gontikr99/bidbot2/controller/plugin"
+	"github.com/gontikr99/bidbot2/controller/storage"
+	"log"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// How long after its preview a run of the DKP policies is applied.  Raid dumps are half an hour apart, so
+// this is the raid dump after the preview.
+const policyPreviewDelay = 25 * time.Minute
+
+// How long after it falls due a previewed run may still be applied.  Later than this, the raid it was planned
+// for is over and the balances it was planned against are out of date, so it's previewed again instead.
+const policyRunExpiry = time.Hour
+
+// A run of the DKP policies waiting to be applied
+type policyRun struct {
+	present   []string // Mains in the raid, to share out spent DKP among
+	decay     bool     // Whether balances decay in this run
+	applyAt   time.Time
+	entries   []storage.LedgerEntry // What was previewed, which is exactly what's applied
+	sharedIDs []uint64              // Award spending shared out by the entries
+}
+
+// Applies the DKP policies to the ledger at raid dumps, previewing each run in Discord first
+type PolicyScheduler struct {
+	eqc *everquest.Client
+	dc  *discord.Client
+	gp  *plugin.GuildPlugin
+
+	sync    sync.Mutex
+	pending *policyRun
+}
+
+func roundDKP(amount float64) float64 {
+	return math.Round(amount*100) / 100
+}
+
+// The ledger entries which carry out the DKP policies, given the ledger's balances, the award spending not
+// yet shared out and the mains present in the raid, along with the IDs of the spending shared out.  Spent DKP
+// is shared first, then balances decay, then they're brought within the minimum and maximum.
+func planPolicies(policy *storage.DKPPolicy, balances map[string]float64, spends []storage.LedgerEntry,
+	present []string, decay bool, now time.Time) (entries []storage.LedgerEntry, sharedIDs []uint64) {
+	entries = make([]storage.LedgerEntry, 0)
+	sharedIDs = make([]uint64, 0)
+	working := make(map[string]float64)
+	for charname, balance := range balances {
+		working[charname] = balance
+	}
+	add := func(charname string, amount float64, kind string, reason string) {
+		amount = roundDKP(amount)
+		if amount == 0 {
+			return
+		}
+		working[charname] += amount
+		entries = append(entries, storage.LedgerEntry{
+			When:      now,
+			Character: charname,
+			Amount:    amount,
+			Kind:      kind,
+			Reason:    reason,
+			By:        "policy",
+		})
+	}
+
+	if policy.ZeroSum && len(present) != 0 && len(spends) != 0 {
+		spent := 0.0
+		for _, spend := range spends {
+			spent -= spend.Amount
+			sharedIDs = append(sharedIDs, spend.ID)
+		}
+		reason := fmt.Sprintf("share of %v DKP spent on %d awards", roundDKP(spent), len(spends))
+		for _, main := range present {
+			add(main, spent/float64(len(present)), storage.LedgerZeroSum, reason)
+		}
+	}
+
+	charnames := make([]string, 0, len(working))
+	for charname := range working {
+		charnames = append(charnames, charname)
+	}
+	sort.Strings(charnames)
+	if decay && policy.DecayPercent != 0 {
+		reason := fmt.Sprintf("%v%% decay", policy.DecayPercent)
+		for _, charname := range charnames {
+			add(charname, -working[charname]*policy.DecayPercent/100, storage.LedgerDecay, reason)
+		}
+	}
+	for _, charname := range charnames {
+		if policy.HasMin && working[charname] < policy.MinBalance {
+			add(charname, policy.MinBalance-working[charname], storage.LedgerCap, fmt.Sprintf("minimum of %v DKP", policy.MinBalance))
+		}
+		if policy.HasMax && working[charname] > policy.MaxBalance {
+			add(charname, policy.MaxBalance-working[charname], storage.LedgerCap, fmt.Sprintf("maximum of %v DKP", policy.MaxBalance))
+		}
+	}
+	return
+}
+
+// Summarize a run's ledger entries by kind, e.g. "zerosum: +300 DKP to 25 mains"
+func summarizePolicyEntries(entries []storage.LedgerEntry) string {
+	kinds := []string{storage.LedgerZeroSum, storage.LedgerDecay, storage.LedgerCap}
+	lines := make([]string, 0, len(kinds))
+	for _, kind := range kinds {
+		total := 0.0
+		count := 0
+		for _, entry := range entries {
+			if entry.Kind == kind {
+				total += entry.Amount
+				count++
+			}
+		}
+		if count != 0 {
+			lines = append(lines, fmt.Sprintf("%v: %+g DKP to %d mains", kind, roundDKP(total), count))
+		}
+	}
+	if len(lines) == 0 {
+		return "Nothing to change"
+	}
+	return strings.Join(lines, "\n")
+}
+
+// Post a report of a run's ledger entries to Discord, with every entry in an attached file
+func (ps *PolicyScheduler) report(title string, entries []storage.LedgerEntry, footer string) {
+	logOnError(ps.dc.WriteComplex(&discordgo.MessageSend{
+		Embed: &discordgo.MessageEmbed{
+			Title:       title,
+			Description: summarizePolicyEntries(entries),
+			Footer:      &discordgo.MessageEmbedFooter{Text: footer},
+			Color:       0x007f00,
+		},
+	}))
+	if len(entries) == 0 {
+		return
+	}
+	buffer := &bytes.Buffer{}
+	for _, entry := range entries {
+		fmt.Fprintf(buffer, "%-15v %-8v %+10g  %v\r\n", inicap(entry.Character), entry.Kind, entry.Amount, entry.Reason)
+	}
+	logOnError(ps.dc.Upload("dkp-policies.txt", buffer.Bytes()))
+}
+
+// Check whether balances are due to decay.  Until decay has started counting (see raidDumped), they aren't.
+func (ps *PolicyScheduler) decayDue(policy *storage.DKPPolicy, now time.Time) bool {
+	if policy.DecayText() == "" {
+		return false
+	}
+	last := ps.eqc.Config.LastDecay()
+	if last.IsZero() {
+		return false
+	}
+	return now.Sub(last) >= policy.DecayEvery
+}
+
+// Work out a run's ledger entries against the current state of the ledger
+func (ps *PolicyScheduler) plan(policy *storage.DKPPolicy, run *policyRun, now time.Time) ([]storage.LedgerEntry, []uint64, error) {
+	balances, err := storage.LedgerBalances()
+	if err != nil {
+		return nil, nil, err
+	}
+	var spends []storage.LedgerEntry
+	if policy.ZeroSum {
+		spends, err = storage.UnsharedSpends()
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+	entries, sharedIDs := planPolicies(policy, balances, spends, run.present, run.decay, now)
+	return entries, sharedIDs, nil
+}
+
+// Called with each periodic raid dump: applies the run previewed at an earlier raid dump once it's due, or
+// previews the next run if the policies have anything to do.  A previewed run which has gone out of date is
+// previewed again rather than applied.
+func (ps *PolicyScheduler) raidDumped(now time.Time, raidDump []byte) {
+	policy := ps.eqc.Config.DKPPolicy()
+	if !policy.Active() {
+		return
+	}
+	ps.sync.Lock()
+	defer ps.sync.Unlock()
+	if ps.pending != nil {
+		if now.Before(ps.pending.applyAt) {
+			return
+		}
+		run := ps.pending
+		ps.pending = nil
+		if now.Sub(run.applyAt) <= policyRunExpiry {
+			if ps.apply(run, now) {
+				return
+			}
+		} else {
+			logOnError(ps.dc.Write("The previewed DKP policy run is out of date, previewing it again"))
+		}
+	}
+	if policy.DecayText() != "" && ps.eqc.Config.LastDecay().IsZero() {
+		// The first time decay is turned on, it starts counting from now
+		ps.eqc.Config.SetLastDecay(now)
+	}
+
+	names, err := storage.StandbyList()
+	if err != nil {
+		log.Printf("Failed to read standby list: %v", err)
+	}
+	for _, member := range everquest.ParseRaidDump(raidDump) {
+		names = append(names, member.Name)
+	}
+	run := &policyRun{
+		present: mainsOf(ps.gp, names),
+		decay:   ps.decayDue(policy, now),
+		applyAt: now.Add(policyPreviewDelay),
+	}
+	run.entries, run.sharedIDs, err = ps.plan(policy, run, now)
+	if err != nil {
+		log.Printf("Failed to plan DKP policies: %v", err)
+		return
+	}
+	if len(run.entries) == 0 {
+		return
+	}
+	ps.pending = run
+	ps.report("DKP policy preview", run.entries, "Applies with the next raid dump unless an officer sends !policy cancel")
+}
+
+// Apply a previewed run to the ledger, exactly as it was previewed.  Anything else which changed the ledger
+// since is left for the next run, except for changes to the award spending the run shares out: then nothing is
+// applied, and false is returned so the run can be previewed again.
+func (ps *PolicyScheduler) apply(run *policyRun, now time.Time) bool {
+	err := storage.ApplyPolicyEntries(run.entries, run.sharedIDs)
+	if err == storage.ErrSpendsChanged {
+		logOnError(ps.dc.Write("Awards changed since the DKP policy preview, previewing it again"))
+		return false
+	} else if err != nil {
+		log.Printf("Failed to apply DKP policies: %v", err)
+		logOnError(ps.dc.Writef("Failed to apply DKP policies: %v", err))
+		return true
+	}
+	if run.decay {
+		ps.eqc.Config.SetLastDecay(now)
+	}
+	ps.report("DKP policies applied", run.entries, fmt.Sprintf("%d ledger entries", len(run.entries)))
+	return true
+}
+
+// Officer commands to preview the DKP policies, or cancel a previewed run.  The returned scheduler applies
+// the policies when given raid dumps.
+func RegisterPolicyCommands(eqc *everquest.Client, dc *discord.Client, gp *plugin.GuildPlugin) *PolicyScheduler {
+	ps := &PolicyScheduler{eqc: eqc, dc: dc, gp: gp}
+	eqc.RegisterCCCommand("!policy", func(who string, args string) {
+		switch strings.ToLower(strings.TrimSpace(args)) {
+		case "":
+			policy := eqc.Config.DKPPolicy()
+			if !policy.Active() {
+				logOnError(eqc.Tell(who, "No DKP policies are turned on."))
+				return
+			}
+			present, err := raidMains(eqc, gp)
+			if err != nil {
+				log.Printf("Failed to read the raid: %v", err)
+				logOnError(eqc.Tell(who, "I couldn't read the raid, sorry."))
+				return
+			}
+			now := time.Now()
+			ps.sync.Lock()
+			entries, _, err := ps.plan(policy, &policyRun{present: present, decay: ps.decayDue(policy, now)}, now)
+			ps.sync.Unlock()
+			if err != nil {
+				log.Printf("Failed to plan DKP policies: %v", err)
+				logOnError(eqc.Tell(who, "An error occurred, sorry."))
+				return
+			}
+			ps.report("DKP policy dry run", entries, "Requested by "+inicap(who)+", nothing has been changed")
+			logOnError(eqc.Tell(who, "Posted a dry run of the DKP policies to Discord."))
+		case "cancel":
+			ps.sync.Lock()
+			run := ps.pending
+			ps.pending = nil
+			ps.sync.Unlock()
+			if run == nil {
+				logOnError(eqc.Tell(who, "There's no DKP policy run waiting to be applied."))
+				return
+			}
+			logOnError(dc.Writef("[%v] Cancelled the previewed DKP policy run", inicap(who)))
+			logOnError(eqc.Tell(who, "Cancelled the DKP policy run."))
+		default:
+			logOnError(eqc.Tell(who, "Usage: !policy [cancel]"))
+		}
+	})
+	return ps
+}
@@ -0,0 +1,51 @@
+package bot
+
+import (
+	"github.com/gontikr99/bidbot2/controller/storage"
+	"testing"
+	"time"
+)
+
+func Test_planPolicies(t *testing.T) {
+	policy, err := storage.ParseDKPPolicy("10/7", "0/100", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	balances := map[string]float64{"alice": 50, "bob": -20, "carol": 120}
+	spends := []storage.LedgerEntry{{ID: 5, Amount: -30, Kind: storage.LedgerAward}}
+	entries, sharedIDs := planPolicies(policy, balances, spends, []string{"alice", "bob", "dave"}, true, time.Now())
+	if len(sharedIDs) != 1 || sharedIDs[0] != 5 {
+		t.Fatalf("Expected the spending to be shared, got %v", sharedIDs)
+	}
+	expected := []struct {
+		charname string
+		kind     string
+		amount   float64
+	}{
+		{"alice", storage.LedgerZeroSum, 10},
+		{"bob", storage.LedgerZeroSum, 10},
+		{"dave", storage.LedgerZeroSum, 10},
+		{"alice", storage.LedgerDecay, -6},
+		{"bob", storage.LedgerDecay, 1},
+		{"carol", storage.LedgerDecay, -12},
+		{"dave", storage.LedgerDecay, -1},
+		{"bob", storage.LedgerCap, 9},
+		{"carol", storage.LedgerCap, -8},
+	}
+	if len(entries) != len(expected) {
+		t.Fatalf("Expected %d entries, got %v", len(expected), entries)
+	}
+	for idx, entry := range entries {
+		if entry.Character != expected[idx].charname || entry.Kind != expected[idx].kind || entry.Amount != expected[idx].amount {
+			t.Errorf("Entry %d: expected %v, got %v", idx, expected[idx], entry)
+		}
+	}
+}
+
+func Test_planPoliciesOff(t *testing.T) {
+	entries, sharedIDs := planPolicies(&storage.DKPPolicy{}, map[string]float64{"alice": 50},
+		[]storage.LedgerEntry{{ID: 5, Amount: -30}}, []string{"alice"}, true, time.Now())
+	if len(entries) != 0 || len(sharedIDs) != 0 {
+		t.Fatalf("Expected no changes, got %v %v", entries, sharedIDs)
+	}
+}
@@ -0,0 +1,262 @@
+package bot
+
+import (
+	"fmt"
+	"github.com/bwmarrin/discordgo"
+	"github.com/gontikr99/bidbot2/controller/discord"
+	"github.com/gontikr99/bidbot2/controller/everquest"
+	"github.com/gontikr99/bidbot2/controller/plugin"
+	"github.com/gontikr99/bidbot2/controller/storage"
+	"log"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Auctions requested with `!auc` go into a persistent queue, and are run one after another.
+type auctionQueue struct {
+	eqc *everquest.Client
+	dc  *discord.Client
+	gp  *plugin.GuildPlugin
+	enc *Encounters
+
+	wake chan struct{}
+
+	sync      sync.Mutex
+	running   bool
+	current   *auction
+	linkTexts map[uint64]string
+}
+
+func RegisterAuctionCommand(eqc *everquest.Client, dc *discord.Client, gp *plugin.GuildPlugin, enc *Encounters) {
+	aq := &auctionQueue{
+		eqc:       eqc,
+		dc:        dc,
+		gp:        gp,
+		enc:       enc,
+		wake:      make(chan struct{}, 1),
+		linkTexts: make(map[uint64]string),
+	}
+
+	eqc.RegisterCCCommand("!auc", func(who string, args string) {
+		open, itemArgs := parseAuctionMode(args)
+		items := parseAuctionItems(itemArgs)
+		if len(items) == 0 {
+			logOnError(eqc.Tell(who, "What did you want me to auction?"))
+			return
+		}
+		if open && (len(items) > 1 || items[0].Count > 1) {
+			logOnError(eqc.Tell(who, "Open auctions are for a single copy of a single item."))
+			return
+		}
+		qa := &storage.QueuedAuction{
+			Items:       items,
+			RequestedBy: who,
+			Queued:      time.Now(),
+			Open:        open,
+		}
+
+		aq.sync.Lock()
+		queue, err := storage.AuctionQueue()
+		if err == nil {
+			err = storage.EnqueueAuction(qa)
+		}
+		if err != nil {
+			aq.sync.Unlock()
+			log.Println(err)
+			logOnError(eqc.Tellf(who, "Sorry, I couldn't queue that auction: %v", err))
+			return
+		}
+		idle := !aq.running && len(queue) == 0
+		if idle {
+			// The request is still the most recent C&C message on screen, so we can click on its link.
+			itemName := items[0].ItemName
+			itemOffset := strings.Index(args, itemName)
+			aq.linkTexts[qa.ID] = "!auc" + args[:itemOffset] + "{" + itemName + "}"
+		}
+		aq.sync.Unlock()
+
+		if !idle {
+			logOnError(eqc.Tellf(who, "Queued auction of %v at position %d", queuedItemNames(qa), len(queue)+1))
+			aq.postQueue()
+		}
+		aq.poke()
+	})
+
+	eqc.RegisterCCCommand("!queue", func(who string, args string) {
+		queue, err := storage.AuctionQueue()
+		if err != nil {
+			log.Println(err)
+			logOnError(eqc.Tell(who, "Sorry, I couldn't read the auction queue."))
+			return
+		}
+		if len(queue) == 0 {
+			logOnError(eqc.Tell(who, "The auction queue is empty."))
+		}
+		for idx, qa := range queue {
+			logOnError(eqc.Tellf(who, "%d: %v (from %v)", idx+1, queuedItemNames(&qa), inicap(qa.RequestedBy)))
+		}
+		aq.postQueue()
+	})
+
+	eqc.RegisterCCCommand("!skip", func(who string, args string) {
+		position, err := strconv.Atoi(strings.TrimSpace(args))
+		if err != nil {
+			logOnError(eqc.Tell(who, "Which queue position did you want me to skip?"))
+			return
+		}
+		aq.sync.Lock()
+		queue, err := storage.AuctionQueue()
+		if err == nil && (position < 1 || position > len(queue)) {
+			err = fmt.Errorf("there is no position %d in the queue", position)
+		}
+		if err == nil {
+			err = storage.RemoveQueuedAuction(queue[position-1].ID)
+			delete(aq.linkTexts, queue[position-1].ID)
+		}
+		aq.sync.Unlock()
+		if err != nil {
+			logOnError(eqc.Tellf(who, "Couldn't skip that auction: %v", err))
+			return
+		}
+		skipped := queue[position-1]
+		logOnError(eqc.Tellf(who, "Skipped %v", queuedItemNames(&skipped)))
+		aq.postQueue()
+	})
+
+	eqc.RegisterCCCommand("!clear", func(who string, args string) {
+		aq.sync.Lock()
+		err := storage.ClearAuctionQueue()
+		aq.linkTexts = make(map[uint64]string)
+		aq.sync.Unlock()
+		if err != nil {
+			log.Println(err)
+			logOnError(eqc.Tellf(who, "Couldn't clear the auction queue: %v", err))
+			return
+		}
+		logOnError(eqc.Tell(who, "Cleared the auction queue."))
+		aq.postQueue()
+	})
+
+	aq.registerControlCommands()
+	aq.registerLootCommands()
+	go aq.run()
+	go aq.watchLoot()
+}
+
+// The names of the items in a queued auction, as plain text
+func queuedItemNames(qa *storage.QueuedAuction) string {
+	names := make([]string, 0, len(qa.Items))
+	for _, qi := range qa.Items {
+		names = append(names, countPrefix(qi.Count)+qi.ItemName)
+	}
+	if qa.Open {
+		return strings.Join(names, ", ") + " (open)"
+	}
+	return strings.Join(names, ", ")
+}
+
+// Queue an auction which wasn't requested with !auc, so has no link to click on
+func (aq *auctionQueue) enqueue(qa *storage.QueuedAuction) error {
+	aq.sync.Lock()
+	err := storage.EnqueueAuction(qa)
+	aq.sync.Unlock()
+	if err != nil {
+		return err
+	}
+	aq.postQueue()
+	aq.poke()
+	return nil
+}
+
+// Let the queue runner know that something may have been added.
+func (aq *auctionQueue) poke() {
+	select {
+	case aq.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Take the next auction off the queue, or nil if there isn't one.
+func (aq *auctionQueue) next() (qa *storage.QueuedAuction, linkText string) {
+	aq.sync.Lock()
+	defer aq.sync.Unlock()
+	queue, err := storage.AuctionQueue()
+	if err != nil {
+		log.Println(err)
+		return
+	}
+	if len(queue) == 0 {
+		return
+	}
+	qa = &queue[0]
+	logOnError(storage.RemoveQueuedAuction(qa.ID))
+	linkText = aq.linkTexts[qa.ID]
+	delete(aq.linkTexts, qa.ID)
+	aq.running = true
+	return
+}
+
+func (aq *auctionQueue) run() {
+	// Pick up anything left in the queue from a previous run.
+	aq.poke()
+	for {
+		select {
+		case <-aq.eqc.Context.Done():
+			return
+		case <-aq.wake:
+		}
+		for {
+			qa, linkText := aq.next()
+			if qa == nil {
+				break
+			}
+			a := newAuction(aq.eqc, aq.dc, aq.gp, aq.enc, qa)
+			aq.sync.Lock()
+			aq.current = a
+			aq.sync.Unlock()
+
+			a.run(linkText)
+
+			aq.sync.Lock()
+			aq.current = nil
+			aq.running = false
+			aq.sync.Unlock()
+
+			select {
+			case <-aq.eqc.Context.Done():
+				return
+			default:
+			}
+			if queue, err := storage.AuctionQueue(); err == nil && len(queue) != 0 {
+				aq.postQueue()
+			}
+		}
+	}
+}
+
+// Post the contents of the queue to the bound Discord channel
+func (aq *auctionQueue) postQueue() {
+	queue, err := storage.AuctionQueue()
+	if err != nil {
+		log.Println(err)
+		return
+	}
+	eb := &discordgo.MessageEmbed{
+		Title: "Auction queue",
+		Color: 0x007f00,
+	}
+	if len(queue) == 0 {
+		eb.Description = "Nothing queued"
+	} else {
+		lines := make([]string, 0, len(queue))
+		for idx, qa := range queue {
+			lines = append(lines, fmt.Sprintf("%d: `%v` (%v)", idx+1,
+				strings.ReplaceAll(queuedItemNames(&qa), "`", "'"), inicap(qa.RequestedBy)))
+		}
+		eb.Description = strings.Join(lines, "\n")
+	}
+	_, err = aq.dc.WriteComplex(&discordgo.MessageSend{Embed: eb})
+	logOnError(err)
+}
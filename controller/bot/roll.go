@@ -0,0 +1,303 @@
+package bot
+
+import (
+	"fmt"
+	"github.com/bwmarrin/discordgo"
+	"github.com/gontikr99/bidbot2/controller/assets"
+	"github.com/gontikr99/bidbot2/controller/discord"
+	"github.com/gontikr99/bidbot2/controller/everquest"
+	"github.com/gontikr99/bidbot2/controller/everquest/events"
+	"github.com/gontikr99/bidbot2/controller/storage"
+	"log"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+var rollArgsRE = regexp.MustCompile(`^(.+?)(?:\s+([0-9]+))?$`)
+
+const (
+	defaultRollRange = 100
+	rollDuration     = 30 * time.Second
+	tieRollDuration  = 20 * time.Second
+	rollWarning      = 10 * time.Second
+
+	// How many rolls to remember, to recognize the same roll showing up in several characters' logs
+	rollSeenLimit = 100
+)
+
+// One /random result
+type roll struct {
+	Roller string
+	Value  int
+}
+
+// A roll-off for an item nobody wants to spend DKP on, decided by /random
+type rollOff struct {
+	eqc *everquest.Client
+	dc  *discord.Client
+
+	itemName    string
+	escape      string
+	rollRange   int
+	requestedBy string
+	start       time.Time
+	zone        string
+	boss        string
+
+	// Only these characters may roll, or anyone if nil
+	eligible map[string]bool
+
+	round    []roll
+	rolled   map[string]bool
+	seen     *logDeduper
+	rolls    []storage.BidDisplay
+	texts    []storage.BidText
+	lastRoll map[string]float64
+}
+
+// Split `!roll` arguments into the item name and the range to roll in
+func parseRollArgs(args string) (itemName string, rollRange int) {
+	parts := rollArgsRE.FindStringSubmatch(strings.TrimSpace(args))
+	if parts == nil {
+		return "", 0
+	}
+	rollRange = defaultRollRange
+	if parts[2] != "" {
+		if value, err := strconv.Atoi(parts[2]); err == nil && value > 0 {
+			rollRange = value
+		} else {
+			return "", 0
+		}
+	}
+	return parts[1], rollRange
+}
+
+func RegisterRollCommand(eqc *everquest.Client, dc *discord.Client, enc *Encounters) {
+	var rollSync sync.Mutex
+	running := false
+	eqc.RegisterCCCommand("!roll", func(who string, args string) {
+		itemName, rollRange := parseRollArgs(args)
+		if itemName == "" {
+			logOnError(eqc.Tell(who, "Usage: !roll <item> [range]"))
+			return
+		}
+		rollSync.Lock()
+		if running {
+			rollSync.Unlock()
+			logOnError(eqc.Tell(who, "There's already a roll-off running."))
+			return
+		}
+		running = true
+		rollSync.Unlock()
+		defer func() {
+			rollSync.Lock()
+			running = false
+			rollSync.Unlock()
+		}()
+
+		ro := &rollOff{
+			eqc:         eqc,
+			dc:          dc,
+			itemName:    itemName,
+			escape:      strings.ReplaceAll(itemName, "`", "'"),
+			rollRange:   rollRange,
+			requestedBy: who,
+			start:       time.Now(),
+			zone:        enc.Zone(),
+			boss:        enc.RecentBoss(),
+			seen:        newLogDeduper(0, rollSeenLimit),
+			rolls:       make([]storage.BidDisplay, 0),
+			texts:       make([]storage.BidText, 0),
+			lastRoll:    make(map[string]float64),
+		}
+		ro.run()
+	})
+}
+
+// Run the roll-off, with further rounds between tied rollers until there's a single winner
+func (ro *rollOff) run() {
+	eqc, dc := ro.eqc, ro.dc
+	rolls, tapDone := eqc.Subscribe(events.RollType)
+	defer tapDone()
+	logOnError(eqc.Tellf(ro.requestedBy, "Starting roll-off on %v", ro.itemName))
+	logOnError(dc.Writef("---- [%v] **Roll Start**: `%v` (0 to %d)", ro.requestedBy, ro.escape, ro.rollRange))
+
+	var tied []roll
+	duration := rollDuration
+	for roundNumber := 1; ; roundNumber++ {
+		if !ro.collect(rolls, roundNumber, duration) {
+			return
+		}
+		if len(ro.round) == 0 {
+			break
+		}
+		tied = topRolls(ro.round)
+		if len(tied) == 1 {
+			break
+		}
+		names := make([]string, 0, len(tied))
+		ro.eligible = make(map[string]bool)
+		for _, r := range tied {
+			names = append(names, inicap(r.Roller))
+			ro.eligible[r.Roller] = true
+		}
+		announce(eqc, dc,
+			[]interface{}{fmt.Sprintf(">> %v tied with %d for %v, roll again! <<", strings.Join(names, ", "), tied[0].Value, ro.itemName)},
+			strings.Join(names, ", ")+" tied.  Roll again!")
+		duration = tieRollDuration
+	}
+	// If nobody rolled again after a tie, the first of the tied rolls wins.
+	winner := ""
+	if len(tied) != 0 {
+		winner = tied[0].Roller
+	}
+	ro.finish(winner)
+}
+
+// Collect rolls for one round, announcing it at the start and shortly before the end.  Returns false if
+// the bot is shutting down.
+func (ro *rollOff) collect(rolls <-chan events.Event, roundNumber int, duration time.Duration) bool {
+	eqc, dc := ro.eqc, ro.dc
+	ro.round = make([]roll, 0)
+	ro.rolled = make(map[string]bool)
+	instructions := fmt.Sprintf("/random %d", ro.rollRange)
+	logOnError(dc.Play(assets.BellTone()))
+	announce(eqc, dc,
+		[]interface{}{fmt.Sprintf(">> Roll for %v: %v.  %v remain. <<", ro.itemName, instructions, secondsText(duration))},
+		fmt.Sprintf("Roll for %v.  %v to go!", ro.escape, secondsText(duration)))
+
+	var warnTimer <-chan time.Time
+	if duration > rollWarning {
+		warnTimer = time.After(duration - rollWarning)
+	}
+	closeTimer := time.After(duration)
+	for {
+		select {
+		case <-eqc.Context.Done():
+			return false
+		case <-warnTimer:
+			announce(eqc, dc,
+				[]interface{}{fmt.Sprintf(">> Roll for %v: %v.  %v remain. <<", ro.itemName, instructions, secondsText(rollWarning))},
+				fmt.Sprintf("Roll for %v.  Last call!", ro.escape))
+		case <-closeTimer:
+			logOnError(dc.Play(assets.BellTone()))
+			announce(eqc, dc,
+				[]interface{}{fmt.Sprintf(">> Rolling closed for %v <<", ro.itemName)},
+				"No more rolls for "+ro.escape+".")
+			return true
+		case event := <-rolls:
+			ro.handleRoll(event.(*events.Roll), roundNumber)
+		}
+	}
+}
+
+// Record a /random if it's valid
+func (ro *rollOff) handleRoll(r *events.Roll, roundNumber int) {
+	roller := strings.ToLower(r.Roller)
+	// The same roll shows up once for every character whose log we're reading.
+	if !ro.seen.first(r.Character, r.Timestamp+" "+roller+" "+r.Message, r.Time) {
+		return
+	}
+
+	low, high, value := r.Low, r.High, r.Value
+	ro.texts = append(ro.texts, storage.BidText{
+		Bidder:   roller,
+		Text:     fmt.Sprintf("rolled %d (%d to %d)", value, low, high),
+		Received: time.Now(),
+	})
+	if low != 0 || high != ro.rollRange {
+		go func() {
+			logOnError(ro.eqc.Tellf(roller, "Your roll from %d to %d doesn't count, please /random %d", low, high, ro.rollRange))
+		}()
+		return
+	}
+	if ro.eligible != nil && !ro.eligible[roller] {
+		go func() { logOnError(ro.eqc.Tell(roller, "Only the tied rollers are rolling again, sorry.")) }()
+		return
+	}
+	if ro.rolled[roller] {
+		go func() { logOnError(ro.eqc.Tell(roller, "You've already rolled, only your first roll counts.")) }()
+		return
+	}
+	ro.rolled[roller] = true
+	ro.round = append(ro.round, roll{Roller: roller, Value: value})
+	ro.lastRoll[roller] = float64(value)
+	desc := strconv.Itoa(value)
+	if roundNumber > 1 {
+		desc = fmt.Sprintf("%d (tie-break %d)", value, roundNumber-1)
+	}
+	ro.rolls = append(ro.rolls, storage.BidDisplay{BidderDesc: inicap(roller), BidDesc: desc})
+}
+
+// The rolls sharing the highest value, in the order they were made
+func topRolls(rolls []roll) []roll {
+	result := make([]roll, 0)
+	for _, r := range rolls {
+		if len(result) == 0 || r.Value > result[0].Value {
+			result = []roll{r}
+		} else if r.Value == result[0].Value {
+			result = append(result, r)
+		}
+	}
+	return result
+}
+
+// Announce and record the winner of the roll-off
+func (ro *rollOff) finish(winner string) {
+	eqc, dc := ro.eqc, ro.dc
+	record := &storage.AuctionRecord{
+		ItemName:  ro.itemName,
+		Count:     1,
+		StartedBy: strings.ToLower(ro.requestedBy),
+		Start:     ro.start,
+		End:       time.Now(),
+		BidTexts:  ro.texts,
+		Bids:      ro.lastRoll,
+		Winners:   []string{},
+		Displays:  ro.rolls,
+		Rolled:    true,
+		Zone:      ro.zone,
+		Boss:      ro.boss,
+	}
+	if winner != "" {
+		record.Winners = []string{winner}
+	}
+	if err := storage.SaveAuctionRecord(record); err != nil {
+		log.Printf("Failed to save roll-off history: %v", err)
+		record = nil
+	}
+
+	description := fmt.Sprintf("`%v`: No rolls", ro.escape)
+	if winner == "" {
+		logOnError(eqc.Announce(fmt.Sprintf(">> Preliminary winner of %v: no rolls <<", ro.itemName)))
+	} else {
+		value := int(ro.lastRoll[winner])
+		logOnError(eqc.Announce(fmt.Sprintf(">> Preliminary winner of %v: %v with a roll of %d. <<", ro.itemName, inicap(winner), value)))
+		description = fmt.Sprintf("`%v`: `%v` rolled %d", ro.escape, inicap(winner), value)
+	}
+	eb := &discordgo.MessageEmbed{
+		Title:       "Roll end",
+		Description: description,
+		Fields:      make([]*discordgo.MessageEmbedField, 0),
+		Color:       0x007f00,
+	}
+	for i := 0; i < 9 && i < len(ro.rolls); i++ {
+		eb.Fields = append(eb.Fields, &discordgo.MessageEmbedField{
+			Name:   "`" + ro.rolls[i].BidderDesc + "`",
+			Value:  ro.rolls[i].BidDesc,
+			Inline: true,
+		})
+	}
+	if winner != "" {
+		eb.Footer = &discordgo.MessageEmbedFooter{Text: "Officers: react with " + confirmEmoji + " or use !confirm to finalize"}
+	}
+	msg, err := dc.WriteComplex(&discordgo.MessageSend{Embed: eb})
+	if err != nil {
+		log.Println(err)
+	} else if record != nil && winner != "" {
+		trackResultMessage(dc, record.ID, msg)
+	}
+}
@@ -0,0 +1,19 @@
+package bot
+
+import "testing"
+
+func Test_parseRollArgs(t *testing.T) {
+	if item, rollRange := parseRollArgs(" Cloak of Flames"); item != "Cloak of Flames" || rollRange != defaultRollRange {
+		t.Fatalf("Expected Cloak of Flames with the default range, got %v %v", item, rollRange)
+	}
+	if item, rollRange := parseRollArgs(" Cloak of Flames 1000"); item != "Cloak of Flames" || rollRange != 1000 {
+		t.Fatalf("Expected Cloak of Flames with range 1000, got %v %v", item, rollRange)
+	}
+}
+
+func Test_topRolls(t *testing.T) {
+	tied := topRolls([]roll{{"alice", 50}, {"bob", 90}, {"carol", 90}, {"dave", 10}})
+	if len(tied) != 2 || tied[0].Roller != "bob" || tied[1].Roller != "carol" {
+		t.Fatalf("Expected bob and carol tied, got %v", tied)
+	}
+}
@@ -0,0 +1,187 @@
+package bot
+
+import (
+	"fmt"
+	"github.com/bwmarrin/discordgo"
+	"github.com/gontikr99/bidbot2/controller/discord"
+	"github.com/gontikr99/bidbot2/controller/everquest"
+	"github.com/gontikr99/bidbot2/controller/storage"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Most roster changes listed in one Discord post
+const maxRosterLines = 30
+
+func altText(isAlt bool) string {
+	if isAlt {
+		return "alt"
+	}
+	return "main"
+}
+
+// The changes between two guild rosters, in alphabetical order of character
+func diffRoster(old map[string]storage.RosterMember, new map[string]storage.RosterMember, when time.Time) []storage.RosterChange {
+	names := make([]string, 0, len(old)+len(new))
+	for name := range old {
+		names = append(names, name)
+	}
+	for name := range new {
+		if _, ok := old[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	changes := make([]storage.RosterChange, 0)
+	add := func(name string, kind string, oldValue string, newValue string) {
+		changes = append(changes, storage.RosterChange{When: when, Character: name, Kind: kind, Old: oldValue, New: newValue})
+	}
+	for _, name := range names {
+		before, wasMember := old[name]
+		after, isMember := new[name]
+		switch {
+		case !wasMember:
+			add(name, storage.RosterJoined, "", after.Rank)
+		case !isMember:
+			add(name, storage.RosterLeft, before.Rank, "")
+		default:
+			if before.Rank != after.Rank {
+				add(name, storage.RosterRank, before.Rank, after.Rank)
+			}
+			if before.IsAlt != after.IsAlt {
+				add(name, storage.RosterAlt, altText(before.IsAlt), altText(after.IsAlt))
+			}
+			if before.GuildNote != after.GuildNote {
+				add(name, storage.RosterNote, before.GuildNote, after.GuildNote)
+			}
+		}
+	}
+	return changes
+}
+
+// Describe a roster change, e.g. "Alice: rank member -> officer"
+func describeRosterChange(change *storage.RosterChange) string {
+	name := inicap(change.Character)
+	switch change.Kind {
+	case storage.RosterJoined:
+		return fmt.Sprintf("%v joined as %v", name, change.New)
+	case storage.RosterLeft:
+		return fmt.Sprintf("%v left (was %v)", name, change.Old)
+	case storage.RosterRank:
+		return fmt.Sprintf("%v: rank %v -> %v", name, change.Old, change.New)
+	case storage.RosterAlt:
+		if change.New == "alt" {
+			return name + ": now an alt"
+		}
+		return name + ": now a main"
+	case storage.RosterNote:
+		return fmt.Sprintf("%v: note '%v' -> '%v'", name, change.Old, change.New)
+	}
+	return fmt.Sprintf("%v: %v", name, change.Kind)
+}
+
+// Compare each guild dump with the last one, recording and posting the differences
+func StartRosterFeed(eqc *everquest.Client, dc *discord.Client) {
+	// Guild dumps are taken one at a time, but their handlers run concurrently
+	var rosterSync sync.Mutex
+	eqc.OnGuildDump(func(records map[string]*everquest.GuildRecord) {
+		rosterSync.Lock()
+		defer rosterSync.Unlock()
+		now := time.Now()
+		snapshot := &storage.RosterSnapshot{Taken: now, Members: make(map[string]storage.RosterMember)}
+		for name, record := range records {
+			snapshot.Members[name] = storage.RosterMember{
+				Rank:      record.Rank,
+				IsAlt:     record.IsAlt,
+				GuildNote: record.GuildNote,
+			}
+		}
+		last, err := storage.LastRoster()
+		if err != nil {
+			log.Printf("Failed to read the last guild roster: %v", err)
+			return
+		}
+		changes := make([]storage.RosterChange, 0)
+		if last != nil {
+			changes = diffRoster(last.Members, snapshot.Members, now)
+		}
+		err = storage.SaveRoster(snapshot, changes)
+		if err != nil {
+			log.Printf("Failed to save the guild roster: %v", err)
+			return
+		}
+		if len(changes) != 0 {
+			postRosterChanges(dc, changes)
+		}
+	})
+
+	dc.RegisterDiscordCommand("!bindroster", func(msg *discordgo.MessageCreate, args string) {
+		dc.Fade(msg.Message)
+		if !dc.IsFromAdmin(msg) {
+			dc.ReplyError(msg, "bindroster", "Only server admins may !bindroster")
+			return
+		}
+		dc.Config.SetRosterChannel(msg.ChannelID)
+		dc.ReplyOK(msg, "bindroster", "Roster changes will be posted to this channel")
+	})
+
+	dc.RegisterDiscordCommand("!unbindroster", func(msg *discordgo.MessageCreate, args string) {
+		dc.Fade(msg.Message)
+		if !dc.IsFromAdmin(msg) {
+			dc.ReplyError(msg, "unbindroster", "Only server admins may !unbindroster")
+			return
+		}
+		dc.Config.SetRosterChannel("")
+		dc.ReplyOK(msg, "unbindroster", "Roster changes will be posted to the bound text channel")
+	})
+
+	dc.RegisterDiscordCommand("!roster", func(msg *discordgo.MessageCreate, args string) {
+		dc.Fade(msg.Message)
+		charname := strings.TrimSpace(args)
+		if charname == "" {
+			dc.ReplyError(msg, "roster", "Whose roster history did you want?")
+			return
+		}
+		changes, err := storage.RosterChangesOf(charname)
+		if err != nil {
+			dc.ReplyError(msg, "roster", "An error occurred looking up roster history, sorry.")
+			log.Printf("Failed to look up roster history: %v", err)
+			return
+		}
+		if len(changes) == 0 {
+			dc.ReplyWarn(msg, "roster", "I haven't seen "+inicap(charname)+"'s guild record change.")
+			return
+		}
+		lines := make([]string, 0, len(changes))
+		for idx := range changes {
+			lines = append(lines, changes[idx].When.Format("2006-01-02 15:04")+": "+describeRosterChange(&changes[idx]))
+		}
+		if len(lines) > maxRosterLines {
+			lines = lines[len(lines)-maxRosterLines:]
+		}
+		dc.ReplyOK(msg, "roster", strings.Join(lines, "\n"))
+	})
+}
+
+// Post roster changes to the roster channel, or the bound text channel if there isn't one
+func postRosterChanges(dc *discord.Client, changes []storage.RosterChange) {
+	lines := make([]string, 0, len(changes))
+	for idx := range changes {
+		if idx == maxRosterLines {
+			lines = append(lines, fmt.Sprintf("... and %d more", len(changes)-maxRosterLines))
+			break
+		}
+		lines = append(lines, describeRosterChange(&changes[idx]))
+	}
+	logOnError(dc.WriteComplexTo(dc.Config.RosterChannel(), &discordgo.MessageSend{
+		Embed: &discordgo.MessageEmbed{
+			Title:       "Roster changes",
+			Description: strings.ReplaceAll(strings.Join(lines, "\n"), "`", "'"),
+			Color:       0x007f00,
+		},
+	}))
+}
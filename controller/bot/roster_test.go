@@ -0,0 +1,41 @@
+package bot
+
+import (
+	"github.com/gontikr99/bidbot2/controller/storage"
+	"testing"
+	"time"
+)
+
+func Test_diffRoster(t *testing.T) {
+	old := map[string]storage.RosterMember{
+		"alice": {Rank: "member"},
+		"bob":   {Rank: "member", IsAlt: true, GuildNote: "Carol"},
+		"dave":  {Rank: "officer"},
+	}
+	new := map[string]storage.RosterMember{
+		"alice": {Rank: "officer"},
+		"bob":   {Rank: "member", IsAlt: true, GuildNote: "Erin"},
+		"frank": {Rank: "recruit", IsAlt: false},
+	}
+	changes := diffRoster(old, new, time.Now())
+	expected := []string{
+		"Alice: rank member -> officer",
+		"Bob: note 'Carol' -> 'Erin'",
+		"Dave left (was officer)",
+		"Frank joined as recruit",
+	}
+	if len(changes) != len(expected) {
+		t.Fatalf("Expected %d changes, got %v", len(expected), changes)
+	}
+	for idx := range changes {
+		if text := describeRosterChange(&changes[idx]); text != expected[idx] {
+			t.Errorf("Expected %q, got %q", expected[idx], text)
+		}
+	}
+
+	changes = diffRoster(map[string]storage.RosterMember{"bob": {Rank: "member"}},
+		map[string]storage.RosterMember{"bob": {Rank: "member", IsAlt: true}}, time.Now())
+	if len(changes) != 1 || describeRosterChange(&changes[0]) != "Bob: now an alt" {
+		t.Fatalf("Expected Bob to become an alt, got %v", changes)
+	}
+}
@@ -0,0 +1,215 @@
+package bot
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Tracks when an auction will close.  The closing time may move while the auction is running: it can be
+// extended, paused and resumed, or restarted from the beginning.
+type timeline struct {
+	sync       sync.Mutex
+	deadline   time.Time
+	paused     bool
+	pausedLeft time.Duration
+	closed     bool
+	stopped    bool // Closed early, i.e. the auction was cancelled
+	generation int
+	changed    chan struct{}
+}
+
+// Outcome of waiting on a timeline
+type waitResult int
+
+const (
+	waitReached   waitResult = iota // The requested point in the timeline was reached
+	waitRestarted                   // The timeline was restarted from the beginning
+	waitCancelled                   // The auction was cancelled, or the bot is shutting down
+)
+
+func newTimeline(duration time.Duration) *timeline {
+	return &timeline{
+		deadline: time.Now().Add(duration),
+		changed:  make(chan struct{}, 1),
+	}
+}
+
+func (tl *timeline) remainingLocked() time.Duration {
+	if tl.paused {
+		return tl.pausedLeft
+	}
+	return tl.deadline.Sub(time.Now())
+}
+
+// How much time is left before the auction closes
+func (tl *timeline) remaining() time.Duration {
+	tl.sync.Lock()
+	defer tl.sync.Unlock()
+	return tl.remainingLocked()
+}
+
+// Which run through the timeline we're on; bumped every time the timeline restarts
+func (tl *timeline) currentGeneration() int {
+	tl.sync.Lock()
+	defer tl.sync.Unlock()
+	return tl.generation
+}
+
+// Make sure at least `minimum` remains before the auction closes.  Returns true if the closing time was moved.
+func (tl *timeline) extendTo(minimum time.Duration) bool {
+	tl.sync.Lock()
+	defer tl.sync.Unlock()
+	newDeadline := time.Now().Add(minimum)
+	if tl.closed || tl.paused || !newDeadline.After(tl.deadline) {
+		return false
+	}
+	tl.deadline = newDeadline
+	tl.notify()
+	return true
+}
+
+// Set the closing time so that exactly `left` remains, whether or not the clock is paused.  Returns false
+// if the auction has already closed.
+func (tl *timeline) reset(left time.Duration) bool {
+	tl.sync.Lock()
+	defer tl.sync.Unlock()
+	if tl.closed {
+		return false
+	}
+	if tl.paused {
+		tl.pausedLeft = left
+	} else {
+		tl.deadline = time.Now().Add(left)
+	}
+	tl.notify()
+	return true
+}
+
+// Push the closing time back by `amount`.  Returns false if the auction has already closed.
+func (tl *timeline) extendBy(amount time.Duration) bool {
+	tl.sync.Lock()
+	defer tl.sync.Unlock()
+	if tl.closed {
+		return false
+	}
+	if tl.paused {
+		tl.pausedLeft += amount
+	} else {
+		tl.deadline = tl.deadline.Add(amount)
+	}
+	tl.notify()
+	return true
+}
+
+// Stop the clock.  Returns false if the auction is already paused or closed.
+func (tl *timeline) pause() bool {
+	tl.sync.Lock()
+	defer tl.sync.Unlock()
+	if tl.closed || tl.paused {
+		return false
+	}
+	tl.pausedLeft = tl.remainingLocked()
+	tl.paused = true
+	tl.notify()
+	return true
+}
+
+// Start the clock again.  Returns false if the auction isn't paused.
+func (tl *timeline) resume() bool {
+	tl.sync.Lock()
+	defer tl.sync.Unlock()
+	if tl.closed || !tl.paused {
+		return false
+	}
+	tl.deadline = time.Now().Add(tl.pausedLeft)
+	tl.paused = false
+	tl.notify()
+	return true
+}
+
+// Go back to the beginning, with `duration` remaining.  Returns false if the auction has already closed.
+func (tl *timeline) restart(duration time.Duration) bool {
+	tl.sync.Lock()
+	defer tl.sync.Unlock()
+	if tl.closed {
+		return false
+	}
+	tl.deadline = time.Now().Add(duration)
+	tl.paused = false
+	tl.generation++
+	tl.notify()
+	return true
+}
+
+// Mark the auction closed, so that the closing time no longer moves.  Returns false if the auction was
+// already closed, or if the timeline was restarted since `generation`.
+func (tl *timeline) close(generation int) bool {
+	tl.sync.Lock()
+	defer tl.sync.Unlock()
+	if tl.closed || tl.generation != generation {
+		return false
+	}
+	tl.closed = true
+	return true
+}
+
+// Close the auction early.  Returns false if it was already closed.
+func (tl *timeline) stop() bool {
+	tl.sync.Lock()
+	defer tl.sync.Unlock()
+	if tl.closed {
+		return false
+	}
+	tl.closed = true
+	tl.stopped = true
+	tl.notify()
+	return true
+}
+
+// Whether the auction was closed early by stop
+func (tl *timeline) isStopped() bool {
+	tl.sync.Lock()
+	defer tl.sync.Unlock()
+	return tl.stopped
+}
+
+// Wake up anyone waiting on the timeline, so they see the new closing time.  Call with `sync` held.
+func (tl *timeline) notify() {
+	select {
+	case tl.changed <- struct{}{}:
+	default:
+	}
+}
+
+// Wait until no more than `left` remains before the auction closes.
+func (tl *timeline) waitUntil(ctx context.Context, left time.Duration, generation int) waitResult {
+	for {
+		tl.sync.Lock()
+		if tl.stopped {
+			tl.sync.Unlock()
+			return waitCancelled
+		}
+		if tl.generation != generation {
+			tl.sync.Unlock()
+			return waitRestarted
+		}
+		paused := tl.paused
+		wait := tl.remainingLocked() - left
+		tl.sync.Unlock()
+
+		var timer <-chan time.Time
+		if !paused {
+			if wait <= 0 {
+				return waitReached
+			}
+			timer = time.After(wait)
+		}
+		select {
+		case <-ctx.Done():
+			return waitCancelled
+		case <-tl.changed:
+		case <-timer:
+		}
+	}
+}
@@ -0,0 +1,96 @@
+package bot
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func Test_timelinePauseResume(t *testing.T) {
+	tl := newTimeline(50 * time.Millisecond)
+	if !tl.pause() {
+		t.Fatal("Expected to be able to pause")
+	}
+	if tl.pause() {
+		t.Fatal("Expected a second pause to fail")
+	}
+	ctx, done := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer done()
+	if result := tl.waitUntil(ctx, 0, 0); result != waitCancelled {
+		t.Fatalf("Expected paused timeline to outlast the context, got %v", result)
+	}
+	if !tl.resume() {
+		t.Fatal("Expected to be able to resume")
+	}
+	if result := tl.waitUntil(context.Background(), 0, 0); result != waitReached {
+		t.Fatalf("Expected resumed timeline to finish, got %v", result)
+	}
+	if !tl.close(0) {
+		t.Fatal("Expected to be able to close")
+	}
+	if tl.extendBy(time.Second) || tl.restart(time.Second) {
+		t.Fatal("Expected closed timeline to stay closed")
+	}
+}
+
+func Test_timelineRestart(t *testing.T) {
+	tl := newTimeline(time.Hour)
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		tl.restart(time.Millisecond)
+	}()
+	if result := tl.waitUntil(context.Background(), 0, 0); result != waitRestarted {
+		t.Fatalf("Expected restart, got %v", result)
+	}
+	if tl.close(0) {
+		t.Fatal("Expected close of an old generation to fail")
+	}
+	if result := tl.waitUntil(context.Background(), 0, 1); result != waitReached {
+		t.Fatalf("Expected restarted timeline to finish, got %v", result)
+	}
+}
+
+func Test_timelineExtend(t *testing.T) {
+	tl := newTimeline(10 * time.Millisecond)
+	if !tl.extendTo(time.Hour) {
+		t.Fatal("Expected extension")
+	}
+	if tl.extendTo(time.Millisecond) {
+		t.Fatal("Expected no extension when enough time remains")
+	}
+	if tl.remaining() < 59*time.Minute {
+		t.Fatalf("Expected about an hour to remain, got %v", tl.remaining())
+	}
+}
+
+func Test_timelineReset(t *testing.T) {
+	tl := newTimeline(time.Hour)
+	if !tl.reset(time.Millisecond) {
+		t.Fatal("Expected to be able to reset")
+	}
+	if result := tl.waitUntil(context.Background(), 0, 0); result != waitReached {
+		t.Fatalf("Expected reset timeline to finish, got %v", result)
+	}
+	tl.pause()
+	tl.reset(time.Minute)
+	if left := tl.remaining(); left != time.Minute {
+		t.Fatalf("Expected a minute left on the paused timeline, got %v", left)
+	}
+	tl.stop()
+	if tl.reset(time.Minute) {
+		t.Fatal("Expected stopped timeline to stay closed")
+	}
+}
+
+func Test_timelineStop(t *testing.T) {
+	tl := newTimeline(time.Hour)
+	if !tl.stop() {
+		t.Fatal("Expected to be able to stop")
+	}
+	if result := tl.waitUntil(context.Background(), 0, 0); result != waitCancelled {
+		t.Fatalf("Expected stopped timeline to be cancelled even before the context is, got %v", result)
+	}
+	if tl.close(0) || !tl.isStopped() {
+		t.Fatal("Expected stopped timeline to stay stopped")
+	}
+}
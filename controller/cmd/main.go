@@ -2,22 +2,80 @@ package main
 
 import (
 	"context"
+	"flag"
 	"github.com/gontikr99/bidbot2/controller/bot"
 	"github.com/gontikr99/bidbot2/controller/discord"
 	"github.com/gontikr99/bidbot2/controller/everquest"
 	"github.com/gontikr99/bidbot2/controller/gui"
+	"github.com/gontikr99/bidbot2/controller/items"
 	"github.com/gontikr99/bidbot2/controller/plugin"
 	"github.com/gontikr99/bidbot2/controller/storage"
 	"log"
+	"os"
 	"runtime"
+	"strings"
 	"sync"
 )
 
+var (
+	replayLog   = flag.String("replay", "", "Replay a recorded eqlog_<character>_<server>.txt instead of playing EverQuest")
+	replaySpeed = flag.Float64("replay-speed", 1, "How many times faster than recorded to replay the log (0 for no delays)")
+	transcript  = flag.String("transcript", "", "Where to write what would have been typed into EverQuest or posted to "+
+		"Discord while replaying (defaults to the replayed log's name with _transcript added)")
+	replayDB = flag.String("replay-db", "", "Where to keep the scratch copy of the database used while replaying "+
+		"(defaults to the replayed log's name with _storage added)")
+)
+
+// Work on a copy of the database while replaying, so the replayed auctions, DKP and attendance aren't kept
+func useReplayDatabase() {
+	scratch := *replayDB
+	if scratch == "" {
+		scratch = strings.TrimSuffix(*replayLog, ".txt") + "_storage"
+	}
+	err := storage.UseScratchCopy(scratch)
+	if err != nil {
+		log.Fatalf("Failed to copy the database to %v: %v", scratch, err)
+	}
+	log.Printf("Using a scratch copy of the database at %v", scratch)
+}
+
+// Start an EverQuest client replaying a recorded log, writing a transcript of what it would have typed
+func newReplayClient(ctx context.Context, cc storage.ControllerConfig) (*everquest.Client, error) {
+	transcriptFile := *transcript
+	if transcriptFile == "" {
+		transcriptFile = strings.TrimSuffix(*replayLog, ".txt") + "_transcript.txt"
+	}
+	fd, err := os.Create(transcriptFile)
+	if err != nil {
+		return nil, err
+	}
+	eqc, err := everquest.NewReplayClient(ctx, cc, *replayLog, *replaySpeed, fd)
+	if err != nil {
+		fd.Close()
+		return nil, err
+	}
+	go func() {
+		<-ctx.Done()
+		fd.Close()
+	}()
+	log.Printf("Replaying %v, writing the transcript to %v", *replayLog, transcriptFile)
+	return eqc, nil
+}
+
 func main() {
 	runtime.GOMAXPROCS(4)
+	flag.Parse()
+	if *replayLog != "" {
+		useReplayDatabase()
+	}
 	gui.RunMainWindow(&storage.BoltholdBackedConfig{},
 		func(ctx context.Context, cc storage.ControllerConfig) {
 			log.Println("Starting")
+			if cc.ItemData() != "" {
+				if err := items.Load(cc.ItemData()); err != nil {
+					log.Printf("Failed to load item data, continuing without it: %v", err)
+				}
+			}
 			gp, err := plugin.NewGuildPlugin(ctx, &storage.DatabaseWebCache{}, cc.RulesLua())
 			if err != nil {
 				log.Printf("Failed to create plugin: %v", err)
@@ -25,27 +83,42 @@ func main() {
 			}
 
 			// Connecting to Discord and setting up EverQuest both take some time, so do both
-			// in parallel.
+			// in parallel.  When replaying, the replay client is created first, as Discord posts go to its
+			// transcript.
 			var eqc *everquest.Client
 			var dc *discord.Client
+			var replay *everquest.Client
+			if *replayLog != "" {
+				replay, err = newReplayClient(ctx, cc)
+				if err != nil {
+					log.Printf("Failed to start replaying: %v", err)
+					return
+				}
+			}
 			errChan := make(chan error, 2)
 			wg := sync.WaitGroup{}
 			wg.Add(1)
 			go func() {
 				defer wg.Done()
 				var errT error
-				eqc, errT = everquest.NewEqClient(ctx, cc)
+				if replay != nil {
+					eqc = replay
+				} else {
+					eqc, errT = everquest.NewEqClient(ctx, cc)
+				}
 				if errT != nil {
 					errChan <- errT
 					log.Printf("Failed to create EverQuest context: %v", err)
 					return
 				}
 
-				errT = eqc.SetupCommandAndControl()
-				if errT != nil {
-					errChan <- errT
-					log.Printf("Failed to set up Command & Control: %v", err)
-					return
+				if !eqc.Replaying() {
+					errT = eqc.SetupCommandAndControl()
+					if errT != nil {
+						errChan <- errT
+						log.Printf("Failed to set up Command & Control: %v", err)
+						return
+					}
 				}
 				gp.SetEqClient(eqc)
 				bot.RegisterLinkCommands(eqc)
@@ -55,7 +128,11 @@ func main() {
 			go func() {
 				defer wg.Done()
 				var errT error
-				dc, errT = discord.NewDiscordClient(ctx, cc)
+				if replay != nil {
+					dc, errT = discord.NewReplayClient(ctx, cc, func(text string) { replay.Transcribe("%v", text) })
+				} else {
+					dc, errT = discord.NewDiscordClient(ctx, cc)
+				}
 				if errT != nil {
 					errChan <- errT
 					log.Printf("Failed to connect to Discord: %v", err)
@@ -63,6 +140,7 @@ func main() {
 				}
 				gp.SetDiscordClient(dc)
 				bot.RegisterDiscordBindCommands(dc)
+				bot.RegisterHistoryCommands(dc)
 			}()
 			wg.Wait()
 
@@ -76,9 +154,21 @@ func main() {
 			}
 
 			bot.RegisterDKPCommands(dc, eqc, gp)
-			bot.RegisterAuctionCommand(eqc, dc, gp)
+			bot.RegisterAttendanceCommands(eqc, dc)
+			bot.RegisterStandbyCommands(eqc, dc)
+			bot.RegisterMainCommands(eqc)
+			bot.StartRosterFeed(eqc, dc)
+			enc := bot.StartEncounterTracking(eqc, dc, gp)
+			bot.RegisterAuctionCommand(eqc, dc, gp, enc)
+			bot.RegisterAwardCommands(eqc, dc, gp)
+			bot.RegisterLedgerCommands(eqc, gp)
+			bot.RegisterRollCommand(eqc, dc, enc)
 			bot.RegisterSayCommands(eqc, dc)
-			bot.StartPeriodicRaidDumps(eqc, dc)
+			policies := bot.RegisterPolicyCommands(eqc, dc, gp)
+			bot.StartPeriodicRaidDumps(eqc, dc, policies)
+			if eqc.Replaying() {
+				eqc.StartReplay()
+			}
 			log.Println("Initialization completed")
 			log.Println("------------------------------")
 			<-ctx.Done()
@@ -27,6 +27,10 @@ type Client struct {
 	voice chan<- *voiceRequest
 
 	cleanup sync.WaitGroup
+
+	// Set when replaying a recorded log
+	transcribe func(text string)
+	replayed   int // Messages transcribed so far
 }
 
 func NewDiscordClient(ctx context.Context, config storage.ControllerConfig) (client *Client, err error) {
@@ -117,6 +121,9 @@ func (dclient *Client) TapChat() (messages <-chan *discordgo.MessageCreate, done
 }
 
 func (dclient *Client) Fade(mc *discordgo.Message) {
+	if mc == nil || dclient.Replaying() {
+		return
+	}
 	dclient.cleanup.Add(1)
 	go func() {
 		select {
@@ -130,7 +137,7 @@ func (dclient *Client) Fade(mc *discordgo.Message) {
 }
 
 func (dclient *Client) ReplyOK(cmd *discordgo.MessageCreate, title string, text string) error {
-	msg, err := dclient.Session.ChannelMessageSendComplex(cmd.ChannelID, &discordgo.MessageSend{
+	msg, err := dclient.WriteComplexTo(cmd.ChannelID, &discordgo.MessageSend{
 		Embed: &discordgo.MessageEmbed{
 			Title:       title,
 			Description: text,
@@ -142,7 +149,7 @@ func (dclient *Client) ReplyOK(cmd *discordgo.MessageCreate, title string, text
 }
 
 func (dclient *Client) ReplyWarn(cmd *discordgo.MessageCreate, title string, text string) error {
-	msg, err := dclient.Session.ChannelMessageSendComplex(cmd.ChannelID, &discordgo.MessageSend{
+	msg, err := dclient.WriteComplexTo(cmd.ChannelID, &discordgo.MessageSend{
 		Embed: &discordgo.MessageEmbed{
 			Title:       title,
 			Description: text,
@@ -154,7 +161,7 @@ func (dclient *Client) ReplyWarn(cmd *discordgo.MessageCreate, title string, tex
 }
 
 func (dclient *Client) ReplyError(cmd *discordgo.MessageCreate, title string, text string) error {
-	msg, err := dclient.Session.ChannelMessageSendComplex(cmd.ChannelID, &discordgo.MessageSend{
+	msg, err := dclient.WriteComplexTo(cmd.ChannelID, &discordgo.MessageSend{
 		Embed: &discordgo.MessageEmbed{
 			Title:       title,
 			Description: text,
@@ -166,12 +173,17 @@ func (dclient *Client) ReplyError(cmd *discordgo.MessageCreate, title string, te
 }
 
 func (dclient *Client) IsFromAdmin(msg *discordgo.MessageCreate) bool {
-	guild, err := dclient.Session.Guild(msg.GuildID)
+	return dclient.IsAdmin(msg.GuildID, msg.Author.ID)
+}
+
+// Check whether the user is the owner or an administrator of the guild
+func (dclient *Client) IsAdmin(guildID string, userID string) bool {
+	guild, err := dclient.Session.Guild(guildID)
 	if err != nil {
 		log.Println("Failed to look up guild")
 		return false
 	}
-	if strings.Compare(msg.Author.ID, guild.OwnerID) == 0 {
+	if strings.Compare(userID, guild.OwnerID) == 0 {
 		return true
 	}
 	for _, role := range guild.Roles {
@@ -179,7 +191,7 @@ func (dclient *Client) IsFromAdmin(msg *discordgo.MessageCreate) bool {
 			continue
 		}
 		for _, m := range guild.Members {
-			if strings.Compare(m.User.ID, msg.Author.ID) != 0 {
+			if strings.Compare(m.User.ID, userID) != 0 {
 				continue
 			}
 			for _, r := range m.Roles {
@@ -198,6 +210,9 @@ func (dclient *Client) Write(text string) (*discordgo.Message, error) {
 	if chanID == "" {
 		return nil, errors.New("No channel selected yet")
 	}
+	if dclient.Replaying() {
+		return dclient.transcribeMessage(chanID, &discordgo.MessageSend{Content: text}), nil
+	}
 	return dclient.Session.ChannelMessageSend(chanID, text)
 }
 
@@ -206,6 +221,9 @@ func (dclient *Client) Upload(name string, data []byte) (*discordgo.Message, err
 	if chanID == "" {
 		return nil, errors.New("No channel selected yet")
 	}
+	if dclient.Replaying() {
+		return dclient.transcribeMessage(chanID, &discordgo.MessageSend{Files: []*discordgo.File{{Name: name}}}), nil
+	}
 	return dclient.Session.ChannelFileSend(chanID, name, bytes.NewReader(data))
 }
 
@@ -218,10 +236,25 @@ func (dclient *Client) WriteComplex(msg *discordgo.MessageSend) (*discordgo.Mess
 	if chanID == "" {
 		return nil, errors.New("No channel selected yet")
 	}
+	return dclient.WriteComplexTo(chanID, msg)
+}
+
+// Send a message to a particular channel, or to the bound text channel if `chanID` is empty
+func (dclient *Client) WriteComplexTo(chanID string, msg *discordgo.MessageSend) (*discordgo.Message, error) {
+	if chanID == "" {
+		return dclient.WriteComplex(msg)
+	}
+	if dclient.Replaying() {
+		return dclient.transcribeMessage(chanID, msg), nil
+	}
 	return dclient.Session.ChannelMessageSendComplex(chanID, msg)
 }
 
 func (dclient *Client) Say(text string) error {
+	if dclient.Replaying() {
+		dclient.transcribe("Discord voice: " + text)
+		return nil
+	}
 	opus, err := soundmanip.Synthesize(dclient.Config.CloudTTSCredPath(), text)
 	if err != nil {
 		return err
@@ -230,6 +263,9 @@ func (dclient *Client) Say(text string) error {
 }
 
 func (dclient *Client) Play(opus *soundmanip.OpusFile) error {
+	if dclient.Replaying() {
+		return nil
+	}
 	errChan := make(chan voiceResponse)
 	vr := &voiceRequest{
 		sound:  opus,
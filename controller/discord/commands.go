@@ -26,3 +26,13 @@ func (dcc *Client) RegisterDiscordCommand(cmd string, callback func(msg *discord
 		}
 	}()
 }
+
+// Call `callback` whenever someone other than the bot adds `emoji` as a reaction to a message
+func (dcc *Client) RegisterReactionHandler(emoji string, callback func(reaction *discordgo.MessageReactionAdd)) {
+	dcc.Session.AddHandler(func(s *discordgo.Session, mra *discordgo.MessageReactionAdd) {
+		if mra.UserID == s.State.User.ID || mra.Emoji.Name != emoji {
+			return
+		}
+		go callback(mra)
+	})
+}
@@ -0,0 +1,68 @@
+package discord
+
+// replay.go: Stand in for Discord while replaying a recorded log, writing what would have been posted to a
+// transcript instead.
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/bwmarrin/discordgo"
+	"github.com/gontikr99/bidbot2/controller/storage"
+	"net/http"
+	"strings"
+)
+
+var errReplaying = errors.New("Discord isn't available while replaying a log")
+
+// Fails every request, so that nothing reaches Discord through the session
+type offlineTransport struct{}
+
+func (offlineTransport) RoundTrip(*http.Request) (*http.Response, error) {
+	return nil, errReplaying
+}
+
+// Create a client which never connects to Discord.  What would have been posted is passed to `transcribe`.
+func NewReplayClient(ctx context.Context, config storage.ControllerConfig, transcribe func(text string)) (*Client, error) {
+	result := &Client{}
+	result.Context = ctx
+	result.Config = config
+	result.transcribe = transcribe
+	session, err := discordgo.New("")
+	if err != nil {
+		return nil, err
+	}
+	session.Client = &http.Client{Transport: offlineTransport{}}
+	result.Session = session
+	result.chatTaps = make(map[int]*tapPair)
+	return result, nil
+}
+
+// Whether this client is standing in for Discord while a log is replayed
+func (dclient *Client) Replaying() bool {
+	return dclient.transcribe != nil
+}
+
+// Transcribe a message instead of sending it, returning a stand-in for the message which would have been posted
+func (dclient *Client) transcribeMessage(chanID string, msg *discordgo.MessageSend) *discordgo.Message {
+	parts := make([]string, 0, 4)
+	if msg.Content != "" {
+		parts = append(parts, msg.Content)
+	}
+	if msg.Embed != nil {
+		parts = append(parts, msg.Embed.Title, msg.Embed.Description)
+		if msg.Embed.Footer != nil {
+			parts = append(parts, msg.Embed.Footer.Text)
+		}
+	}
+	for _, file := range msg.Files {
+		parts = append(parts, "(file "+file.Name+")")
+	}
+	dclient.transcribe("Discord: " + strings.ReplaceAll(strings.Join(parts, " | "), "\n", " / "))
+
+	dclient.chatSync.Lock()
+	dclient.replayed++
+	id := dclient.replayed
+	dclient.chatSync.Unlock()
+	return &discordgo.Message{ID: fmt.Sprintf("replay-%d", id), ChannelID: chanID, Content: msg.Content}
+}
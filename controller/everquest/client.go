@@ -9,6 +9,7 @@ import (
 	"github.com/gontikr99/bidbot2/controller/imagemanip"
 	"github.com/gontikr99/bidbot2/controller/storage"
 	"image"
+	"io"
 	"log"
 	"regexp"
 	"runtime"
@@ -26,14 +27,27 @@ type Client struct {
 	Config  storage.ControllerConfig
 	Context context.Context
 
-	logChan    <-chan EqLogEntry
-	logSync    sync.Mutex
-	nextLogTap int
-	logTaps    map[int]*tapPair
+	botName      string // Character whose log has the bot's tells and channel messages
+	logChan      <-chan EqLogEntry
+	logSync      sync.Mutex
+	nextLogTap   int
+	logTaps      map[int]*tapPair
+	logTimestamp string // Of the latest entry forwarded
+
+	// Set when replaying a recorded log
+	transcript     io.Writer
+	transcriptSync sync.Mutex
+	replayStart    sync.Once
+	replayLine     *strings.Builder // What's been "typed" by the Send in progress
 
 	guildRecordsSync     sync.Mutex
 	guildRecordTimestamp time.Time
 	guildRecords         map[string]*GuildRecord
+	guildDumpHandlers    []func(map[string]*GuildRecord)
+
+	raidRecordsSync     sync.Mutex
+	raidRecordTimestamp time.Time
+	raidRecords         map[string]*storage.RaidMemberRecord
 
 	typeSync sync.Mutex
 }
@@ -43,7 +57,8 @@ func NewEqClient(ctx context.Context, config storage.ControllerConfig) (client *
 	client = &Client{}
 	client.Config = config
 	client.Context = ctx
-	client.logChan, err = readAllLogs(ctx, client.Config.EverQuestDirectory()+"/Logs")
+	client.botName, _ = storage.BotCharacter(config)
+	client.logChan, err = readAllLogs(ctx, client.Config.EverQuestDirectory()+"/Logs", followedLogs(config))
 	if err != nil {
 		return
 	}
@@ -57,8 +72,14 @@ func NewEqClient(ctx context.Context, config storage.ControllerConfig) (client *
 func (eqc *Client) forwardLogMessages() {
 	for {
 		select {
-		case msg := <-eqc.logChan:
+		case msg, ok := <-eqc.logChan:
+			if !ok {
+				log.Println("Reached the end of the replayed log")
+				eqc.logChan = nil
+				continue
+			}
 			eqc.logSync.Lock()
+			eqc.logTimestamp = msg.Timestamp
 			for k, tap := range eqc.logTaps {
 				select {
 				case <-tap.done:
@@ -120,16 +141,21 @@ type EqInput struct {
 
 func (eqc *Client) GrabInput() (result EqInput, err error) {
 	eqc.typeSync.Lock()
-	err = raiseEverquest()
-	if err != nil {
-		eqc.typeSync.Unlock()
-		return
+	if !eqc.Replaying() {
+		err = raiseEverquest()
+		if err != nil {
+			eqc.typeSync.Unlock()
+			return
+		}
 	}
 	result = EqInput{eqc}
 	return
 }
 
 func (eqc *Client) Raise() error {
+	if eqc.Replaying() {
+		return nil
+	}
 	return raiseEverquest()
 }
 
@@ -157,6 +183,9 @@ func (eqi EqInput) Release() {
 
 // Press the ESC key a bunch of times to close down any temporary windows
 func (eqi EqInput) ClearWindows() {
+	if eqi.client.Replaying() {
+		return
+	}
 	submitKbMouse(func() {
 		for i := 0; i < escCount; i++ {
 			tap('\x1b')
@@ -182,6 +211,9 @@ func (eqi EqInput) Send(parts ...interface{}) error {
 	default:
 		break
 	}
+	if eqi.client.Replaying() {
+		return eqi.sendToTranscript(parts...)
+	}
 	submitKbMouse(func() {
 		// Ensure we've got a totally clear entry line.
 		tapSlow('\n')
@@ -211,6 +243,34 @@ func (eqi EqInput) Send(parts ...interface{}) error {
 	return nil
 }
 
+// Write what Send would have typed to the transcript
+func (eqi EqInput) sendToTranscript(parts ...interface{}) error {
+	line := &strings.Builder{}
+	eqi.client.replayLine = line
+	defer func() { eqi.client.replayLine = nil }()
+	for _, part := range parts {
+		switch v := part.(type) {
+		case string:
+			line.WriteString(v)
+		case func(EqInput):
+			v(eqi)
+		default:
+			return fmt.Errorf("Don't know how to deal with a %v", v)
+		}
+	}
+	eqi.client.Transcribe("%v", line.String())
+	return nil
+}
+
+// Type some text into the line being sent
+func (eqi EqInput) typewrite(text string) {
+	if eqi.client.replayLine != nil {
+		eqi.client.replayLine.WriteString(text)
+		return
+	}
+	typewrite(text)
+}
+
 // Click at the specified point in the client
 func (eqi EqInput) ClickAt(x int, y int) (err error) {
 	select {
@@ -219,6 +279,10 @@ func (eqi EqInput) ClickAt(x int, y int) (err error) {
 	default:
 		break
 	}
+	if eqi.client.Replaying() {
+		eqi.client.Transcribe("(click at %d, %d)", x, y)
+		return nil
+	}
 	submitKbMouse(func() {
 		l, t, _, _, err := getEqClientArea()
 		if err != nil {
@@ -243,6 +307,10 @@ func (eqc *Client) ClickAt(x int, y int) error {
 }
 
 func (eqc *Client) Capture(portion image.Rectangle) (img image.Image, err error) {
+	if eqc.Replaying() {
+		err = errReplaying
+		return
+	}
 	err = raiseEverquest()
 	if err != nil {
 		return
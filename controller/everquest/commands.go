@@ -1,24 +1,39 @@
 package everquest
 
 import (
+	"github.com/gontikr99/bidbot2/controller/everquest/events"
 	"github.com/gontikr99/bidbot2/controller/storage"
-	"regexp"
+	"strings"
+	"unicode"
 )
 
+// The arguments following `command` at the start of a message, and whether the message is that command at all
+func commandArgs(command string, text string) (string, bool) {
+	if !strings.HasPrefix(text, command) {
+		return "", false
+	}
+	args := text[len(command):]
+	if args != "" && !unicode.IsSpace(rune(args[0])) {
+		return "", false
+	}
+	return args, true
+}
+
 // Start a command handler which watches the C&C channel
 func (eqc *Client) RegisterCCCommand(command string, callback func(string, string)) {
-	rxp := regexp.MustCompile("^([A-Za-z]+) tells " + storage.ChannelName(eqc.Config) + ":1, '" + command + "((?:\\s+.*))?'$")
+	channel := storage.ChannelName(eqc.Config)
 	go func() {
-		tap, done := eqc.TapLog()
+		messages, done := eqc.Subscribe(events.ChannelMessageType)
 		defer done()
 		for {
 			select {
-			case msg := <-tap:
-				parts := rxp.FindStringSubmatch(msg.Message)
-				if parts != nil {
-					who := parts[1]
-					args := parts[2]
-					go callback(who, args)
+			case event := <-messages:
+				msg := event.(*events.ChannelMessage)
+				if !eqc.IsBotLog(msg.LogEntry) || !strings.EqualFold(msg.Channel, channel) || msg.Number != 1 {
+					continue
+				}
+				if args, ok := commandArgs(command, msg.Text); ok {
+					go callback(msg.From, args)
 				}
 			case <-eqc.Context.Done():
 				return
@@ -29,18 +44,18 @@ func (eqc *Client) RegisterCCCommand(command string, callback func(string, strin
 
 // Start a command handler which watches tells
 func (eqc *Client) RegisterTellCommand(command string, callback func(string, string)) {
-	rxp := regexp.MustCompile("^([A-Za-z]+) (?:tells|told) you, '" + command + "((?:\\s+.*))?'$")
 	go func() {
-		tap, done := eqc.TapLog()
+		tells, done := eqc.Subscribe(events.TellType)
 		defer done()
 		for {
 			select {
-			case msg := <-tap:
-				parts := rxp.FindStringSubmatch(msg.Message)
-				if parts != nil {
-					who := parts[1]
-					args := parts[2]
-					go callback(who, args)
+			case event := <-tells:
+				msg := event.(*events.Tell)
+				if !eqc.IsBotLog(msg.LogEntry) {
+					continue
+				}
+				if args, ok := commandArgs(command, msg.Text); ok {
+					go callback(msg.From, args)
 				}
 			case <-eqc.Context.Done():
 				return
@@ -2,10 +2,11 @@ package everquest
 
 import (
 	"errors"
+	"github.com/gontikr99/bidbot2/controller/everquest/events"
+	"github.com/gontikr99/bidbot2/controller/storage"
 	"io/ioutil"
 	"log"
 	"os"
-	"regexp"
 	"strconv"
 	"strings"
 	"time"
@@ -15,10 +16,17 @@ type GuildRecordsReader interface {
 	GuildRecords() (gr map[string]*GuildRecord, err error)
 }
 
+type RaidMembersReader interface {
+	RaidMembers() (members map[string]*storage.RaidMemberRecord, err error)
+}
+
 const (
 	dumpReadTimeout = 2 * time.Second
 	dumpRetryCount  = 3
 	windowOpenTime  = 400 * time.Millisecond
+
+	// The raid changes more often than the guild, so raid dumps aren't reused for as long
+	raidRecordsLifetime = time.Minute
 )
 
 func (eqi *EqInput) blinkGuildWindow() {
@@ -49,8 +57,6 @@ func (eqi *EqInput) blinkRaidWindow() {
 	time.Sleep(tapDelay)
 }
 
-var outputfileComplete = regexp.MustCompile("^Outputfile Complete: (.+)$")
-
 func readGuildRecords(filename string) (records map[string]*GuildRecord, err error) {
 	fileText, err := ioutil.ReadFile(filename)
 	if err != nil {
@@ -87,6 +93,8 @@ func (eqc *Client) GuildRecords() (gr map[string]*GuildRecord, err error) {
 		if errt == nil {
 			gr = grt
 			return
+		} else if errt == errReplaying {
+			return nil, errt
 		}
 	}
 	err = errt
@@ -102,11 +110,15 @@ func (eqc *Client) guildRecordsAttempt() (gr map[string]*GuildRecord, err error)
 	}
 	eqc.guildRecordsSync.Unlock()
 
+	if eqc.Replaying() {
+		eqc.Transcribe("/outputfile guild (skipped while replaying)")
+		return nil, errReplaying
+	}
 	eqi, err := eqc.GrabInput()
 	if err != nil {
 		return
 	}
-	tap, tapDone := eqc.TapLog()
+	tap, tapDone := eqc.Subscribe(events.OutputFileCompleteType)
 	defer tapDone()
 	eqi.blinkGuildWindow()
 	eqi.Send("/outputfile guild")
@@ -116,13 +128,12 @@ func (eqc *Client) guildRecordsAttempt() (gr map[string]*GuildRecord, err error)
 			err = errors.New("Shutting down")
 			eqi.Release()
 			return
-		case msg := <-tap:
-			parts := outputfileComplete.FindStringSubmatch(msg.Message)
-			if len(parts) == 0 {
+		case event := <-tap:
+			if !eqc.IsBotLog(event.Logged().LogEntry) {
 				break
 			}
 			eqi.Release()
-			filename := eqc.Config.EverQuestDirectory() + "\\" + parts[1]
+			filename := eqc.Config.EverQuestDirectory() + "\\" + event.(*events.OutputFileComplete).Filename
 			var records map[string]*GuildRecord
 			time.Sleep(250 * time.Millisecond) // let EQ close the file.
 			records, err = readGuildRecords(filename)
@@ -136,6 +147,9 @@ func (eqc *Client) guildRecordsAttempt() (gr map[string]*GuildRecord, err error)
 			defer eqc.guildRecordsSync.Unlock()
 			eqc.guildRecords = records
 			eqc.guildRecordTimestamp = time.Now()
+			for _, handler := range eqc.guildDumpHandlers {
+				go handler(records)
+			}
 			return
 		case <-time.After(10 * time.Millisecond):
 			break
@@ -146,6 +160,14 @@ func (eqc *Client) guildRecordsAttempt() (gr map[string]*GuildRecord, err error)
 	return
 }
 
+// Register a function to be called with each new guild dump, e.g. to compare it with the previous one.  The
+// records must not be modified.
+func (eqc *Client) OnGuildDump(handler func(records map[string]*GuildRecord)) {
+	eqc.guildRecordsSync.Lock()
+	defer eqc.guildRecordsSync.Unlock()
+	eqc.guildDumpHandlers = append(eqc.guildDumpHandlers, handler)
+}
+
 type GuildRecord struct {
 	Present    bool
 	Level      int
@@ -156,12 +178,79 @@ type GuildRecord struct {
 	GuildNote  string
 }
 
+// Parse the contents of a raid dump.  Each line holds the group number, name, level, class and raid role,
+// separated by tabs.
+func ParseRaidDump(raidData []byte) []storage.RaidMemberRecord {
+	members := make([]storage.RaidMemberRecord, 0)
+	for _, line := range strings.Split(string(raidData), "\n") {
+		fields := strings.Split(strings.TrimRight(line, "\r"), "\t")
+		if len(fields) < 4 || fields[1] == "" {
+			continue
+		}
+		group, _ := strconv.Atoi(fields[0])
+		level, _ := strconv.Atoi(fields[2])
+		member := storage.RaidMemberRecord{
+			Group: group,
+			Name:  strings.ToLower(fields[1]),
+			Level: level,
+			Class: strings.ToLower(fields[3]),
+		}
+		if len(fields) > 4 {
+			member.Role = fields[4]
+		}
+		members = append(members, member)
+	}
+	return members
+}
+
+// The current raid members, keyed by lower case name.  A recent raid dump is reused rather than taking
+// another one.
+func (eqc *Client) RaidMembers() (members map[string]*storage.RaidMemberRecord, err error) {
+	eqc.raidRecordsSync.Lock()
+	if eqc.raidRecords != nil && eqc.raidRecordTimestamp.Add(raidRecordsLifetime).After(time.Now()) {
+		defer eqc.raidRecordsSync.Unlock()
+		return eqc.raidRecords, nil
+	}
+	eqc.raidRecordsSync.Unlock()
+
+	for i := 0; i < dumpRetryCount; i++ {
+		_, err = eqc.RaidDump()
+		if err == nil || err == errReplaying {
+			break
+		}
+	}
+	if err != nil {
+		return
+	}
+	eqc.raidRecordsSync.Lock()
+	defer eqc.raidRecordsSync.Unlock()
+	return eqc.raidRecords, nil
+}
+
+// Remember the members found in a raid dump, for RaidMembers
+func (eqc *Client) cacheRaidMembers(raidData []byte) {
+	members := make(map[string]*storage.RaidMemberRecord)
+	for _, member := range ParseRaidDump(raidData) {
+		member := member
+		members[member.Name] = &member
+	}
+	log.Printf("Parsed raid dump, found %v members", len(members))
+	eqc.raidRecordsSync.Lock()
+	defer eqc.raidRecordsSync.Unlock()
+	eqc.raidRecords = members
+	eqc.raidRecordTimestamp = time.Now()
+}
+
 func (eqc *Client) RaidDump() (raidData []byte, err error) {
+	if eqc.Replaying() {
+		eqc.Transcribe("/outputfile raid (skipped while replaying)")
+		return nil, errReplaying
+	}
 	eqi, err := eqc.GrabInput()
 	if err != nil {
 		return
 	}
-	tap, tapDone := eqc.TapLog()
+	tap, tapDone := eqc.Subscribe(events.OutputFileCompleteType)
 	defer tapDone()
 	eqi.blinkRaidWindow()
 	eqi.Send("/outputfile raid")
@@ -171,17 +260,17 @@ func (eqc *Client) RaidDump() (raidData []byte, err error) {
 			err = errors.New("Shutting down")
 			eqi.Release()
 			return
-		case msg := <-tap:
-			parts := outputfileComplete.FindStringSubmatch(msg.Message)
-			if len(parts) == 0 {
+		case event := <-tap:
+			if !eqc.IsBotLog(event.Logged().LogEntry) {
 				break
 			}
 			eqi.Release()
-			filename := eqc.Config.EverQuestDirectory() + "\\" + parts[1]
+			filename := eqc.Config.EverQuestDirectory() + "\\" + event.(*events.OutputFileComplete).Filename
 			time.Sleep(250 * time.Millisecond) // let EQ close the file.
 			raidData, err = ioutil.ReadFile(filename)
 			if err == nil {
 				os.Remove(filename)
+				eqc.cacheRaidMembers(raidData)
 			}
 			return
 		case <-time.After(10 * time.Millisecond):
@@ -0,0 +1,194 @@
+package events
+
+// events.go: The kinds of things EverQuest writes to its logs which BidBot2 cares about.
+
+import "time"
+
+// A line read from an EverQuest log
+type LogEntry struct {
+	Character string
+	Server    string
+	Timestamp string
+	Message   string
+}
+
+// EverQuest log timestamps, e.g. "Sat Oct 17 20:15:03 2026"
+const timestampLayout = "Mon Jan _2 15:04:05 2006"
+
+// Read the timestamp of a log entry, which EverQuest writes in local time
+func ParseTimestamp(timestamp string) (time.Time, error) {
+	return time.ParseInLocation(timestampLayout, timestamp, time.Local)
+}
+
+type Type string
+
+const (
+	TellType               Type = "tell"
+	ChannelMessageType     Type = "channel"
+	GuildSayType           Type = "guild"
+	RaidSayType            Type = "raid"
+	GroupSayType           Type = "group"
+	SayType                Type = "say"
+	ShoutType              Type = "shout"
+	OOCType                Type = "ooc"
+	AuctionType            Type = "auction"
+	RollType               Type = "roll"
+	LootType               Type = "loot"
+	ZoneChangeType         Type = "zone"
+	SlainType              Type = "slain"
+	OutputFileCompleteType Type = "outputfile"
+	NotOnlineType          Type = "notonline"
+	ChannelListType        Type = "channellist"
+	NoChannelsType         Type = "nochannels"
+	BadChannelPasswordType Type = "badpassword"
+)
+
+// Something which happened, as recorded in a log
+type Event interface {
+	Type() Type
+	Logged() *Meta
+}
+
+// Where and when an event was logged
+type Meta struct {
+	LogEntry           // The line the event was read from
+	Time     time.Time // When it was logged, or the zero time if the timestamp couldn't be read
+}
+
+func (m *Meta) Logged() *Meta { return m }
+
+// A tell to the character whose log this is
+type Tell struct {
+	Meta
+	From string
+	Text string
+}
+
+// A message sent to a numbered chat channel, e.g. "Alice tells mybidbot:1, '!auc ...'"
+type ChannelMessage struct {
+	Meta
+	From    string
+	Channel string
+	Number  int
+	Text    string
+}
+
+type GuildSay struct {
+	Meta
+	From string
+	Text string
+}
+
+type RaidSay struct {
+	Meta
+	From string
+	Text string
+}
+
+type GroupSay struct {
+	Meta
+	From string
+	Text string
+}
+
+type Say struct {
+	Meta
+	From string
+	Text string
+}
+
+type Shout struct {
+	Meta
+	From string
+	Text string
+}
+
+type OOC struct {
+	Meta
+	From string
+	Text string
+}
+
+type Auction struct {
+	Meta
+	From string
+	Text string
+}
+
+// A /random result, assembled from the two lines EverQuest logs for it
+type Roll struct {
+	Meta
+	Roller string
+	Low    int
+	High   int
+	Value  int
+}
+
+// An item being looted.  Loot by "You" is attributed to the character whose log this is.
+type Loot struct {
+	Meta
+	Looter   string
+	ItemName string
+	Count    int
+}
+
+// The character whose log this is entering a zone
+type ZoneChange struct {
+	Meta
+	Zone string
+}
+
+// Something dying.  Kills by "You" are attributed to the character whose log this is.
+type Slain struct {
+	Meta
+	Mob    string
+	Killer string
+}
+
+// A guild or raid dump written by /outputfile, named relative to the EverQuest directory
+type OutputFileComplete struct {
+	Meta
+	Filename string
+}
+
+// A tell which couldn't be delivered
+type NotOnline struct {
+	Meta
+	Name string
+}
+
+// The reply to /list when in at least one chat channel
+type ChannelList struct {
+	Meta
+	Channels string
+}
+
+// The reply to /list or /leave when in no chat channels
+type NoChannels struct {
+	Meta
+}
+
+// A failed /join
+type BadChannelPassword struct {
+	Meta
+	Channel string
+}
+
+func (*Tell) Type() Type               { return TellType }
+func (*ChannelMessage) Type() Type     { return ChannelMessageType }
+func (*GuildSay) Type() Type           { return GuildSayType }
+func (*RaidSay) Type() Type            { return RaidSayType }
+func (*GroupSay) Type() Type           { return GroupSayType }
+func (*Say) Type() Type                { return SayType }
+func (*Shout) Type() Type              { return ShoutType }
+func (*OOC) Type() Type                { return OOCType }
+func (*Auction) Type() Type            { return AuctionType }
+func (*Roll) Type() Type               { return RollType }
+func (*Loot) Type() Type               { return LootType }
+func (*ZoneChange) Type() Type         { return ZoneChangeType }
+func (*Slain) Type() Type              { return SlainType }
+func (*OutputFileComplete) Type() Type { return OutputFileCompleteType }
+func (*NotOnline) Type() Type          { return NotOnlineType }
+func (*ChannelList) Type() Type        { return ChannelListType }
+func (*NoChannels) Type() Type         { return NoChannelsType }
+func (*BadChannelPassword) Type() Type { return BadChannelPasswordType }
@@ -0,0 +1,170 @@
+package events
+
+import (
+	"context"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+const testTimestamp = "Sat Oct 17 20:15:03 2026"
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		message  string
+		expected Event
+	}{
+		{"Alice tells you, '10 dkp'", &Tell{From: "Alice", Text: "10 dkp"}},
+		{"Alice told you, 'AFK Message: back soon'", &Tell{From: "Alice", Text: "AFK Message: back soon"}},
+		{"Alice tells mybidbot:1, '!auc Cloak of Flames'", &ChannelMessage{From: "Alice", Channel: "mybidbot", Number: 1, Text: "!auc Cloak of Flames"}},
+		{"Alice tells the guild, 'grats'", &GuildSay{From: "Alice", Text: "grats"}},
+		{"Alice tells the raid,  'pull in 5'", &RaidSay{From: "Alice", Text: "pull in 5"}},
+		{"Alice tells the group, 'inc'", &GroupSay{From: "Alice", Text: "inc"}},
+		{"Alice says, 'hail'", &Say{From: "Alice", Text: "hail"}},
+		{"Alice shouts, 'train to zone'", &Shout{From: "Alice", Text: "train to zone"}},
+		{"Alice says out of character, 'LFG'", &OOC{From: "Alice", Text: "LFG"}},
+		{"Alice auctions, 'WTS Cloak of Flames'", &Auction{From: "Alice", Text: "WTS Cloak of Flames"}},
+		{"--You have looted a Cloak of Flames.--", &Loot{Looter: "Bidbot", ItemName: "Cloak of Flames", Count: 1}},
+		{"--Alice has looted an Ethereal Mist Cloak.--", &Loot{Looter: "Alice", ItemName: "Ethereal Mist Cloak", Count: 1}},
+		{"--Alice has looted 2 Bone Chips.--", &Loot{Looter: "Alice", ItemName: "Bone Chips", Count: 2}},
+		{"Alice tells you, '--You have looted a Cloak of Flames.--'", &Tell{From: "Alice", Text: "--You have looted a Cloak of Flames.--"}},
+		{"You have entered The Plane of Sky.", &ZoneChange{Zone: "The Plane of Sky"}},
+		{"You have entered Veeshan's Peak.", &ZoneChange{Zone: "Veeshan's Peak"}},
+		{"You have entered an area where levitation effects do not function.", nil},
+		{"You have entered an Arena (PvP) area.", nil},
+		{"Lord Nagafen has been slain by Alice!", &Slain{Mob: "Lord Nagafen", Killer: "Alice"}},
+		{"You have slain a gnoll pup!", &Slain{Mob: "a gnoll pup", Killer: "Bidbot"}},
+		{"Outputfile Complete: RaidRoster_xegony-20261017-201503.txt", &OutputFileComplete{Filename: "RaidRoster_xegony-20261017-201503.txt"}},
+		{"Joramar is not online at this time.", &NotOnline{Name: "Joramar"}},
+		{"Channels: 1=mybidbot(3)", &ChannelList{Channels: "1=mybidbot(3)"}},
+		{"You are not on any channels", &NoChannels{}},
+		{"Incorrect password for channel mybidbot.", &BadChannelPassword{Channel: "mybidbot"}},
+		{"Your faction standing with Guards of Qeynos got worse.", nil},
+	}
+	for _, test := range tests {
+		entry := LogEntry{Character: "Bidbot", Server: "xegony", Timestamp: testTimestamp, Message: test.message}
+		event := Parse(entry)
+		if test.expected == nil {
+			if event != nil {
+				t.Errorf("%v: expected nothing, got %#v", test.message, event)
+			}
+			continue
+		}
+		if event == nil {
+			t.Errorf("%v: expected %v, got nothing", test.message, test.expected.Type())
+			continue
+		}
+		*test.expected.Logged() = *event.Logged()
+		if !reflect.DeepEqual(event, test.expected) {
+			t.Errorf("%v: expected %#v, got %#v", test.message, test.expected, event)
+		}
+		if event.Logged().Message != test.message || event.Logged().Character != "Bidbot" {
+			t.Errorf("%v: lost the log entry, got %#v", test.message, event.Logged())
+		}
+	}
+}
+
+func TestParseTimestamp(t *testing.T) {
+	tests := map[string]time.Time{
+		"Sat Oct 17 20:15:03 2026": time.Date(2026, time.October, 17, 20, 15, 3, 0, time.Local),
+		"Wed Oct 07 09:05:00 2026": time.Date(2026, time.October, 7, 9, 5, 0, 0, time.Local),
+		"Wed Oct  7 09:05:00 2026": time.Date(2026, time.October, 7, 9, 5, 0, 0, time.Local),
+	}
+	for timestamp, expected := range tests {
+		parsed, err := ParseTimestamp(timestamp)
+		if err != nil || !parsed.Equal(expected) {
+			t.Errorf("%v: expected %v, got %v (%v)", timestamp, expected, parsed, err)
+		}
+	}
+	if event := Parse(LogEntry{Timestamp: "garbage", Message: "You have entered The Plane of Sky."}); event == nil || !event.Logged().Time.IsZero() {
+		t.Errorf("Expected a zero time for a bad timestamp, got %v", event)
+	}
+}
+
+func TestParser_Roll(t *testing.T) {
+	parser := NewParser()
+	lines := []LogEntry{
+		{Character: "Bidbot", Message: "**A Magic Die is rolled by Alice.**"},
+		{Character: "Officer", Message: "**A Magic Die is rolled by Bob."},
+		{Character: "Bidbot", Message: "**It could have been any number from 0 to 100, but this time it turned up a 42.**"},
+		{Character: "Officer", Message: "**It could have been any number from 0 to 1000, but this time it turned up a 999."},
+		{Character: "Bidbot", Message: "**It could have been any number from 0 to 100, but this time it turned up a 7.**"},
+	}
+	var rolls []*Roll
+	for _, line := range lines {
+		if event := parser.Parse(line); event != nil {
+			rolls = append(rolls, event.(*Roll))
+		}
+	}
+	if len(rolls) != 2 {
+		t.Fatalf("Expected 2 rolls, got %v", len(rolls))
+	}
+	if r := rolls[0]; r.Roller != "Alice" || r.Low != 0 || r.High != 100 || r.Value != 42 {
+		t.Errorf("Unexpected first roll %#v", r)
+	}
+	if r := rolls[1]; r.Roller != "Bob" || r.High != 1000 || r.Value != 999 {
+		t.Errorf("Unexpected second roll %#v", r)
+	}
+}
+
+type testSource chan LogEntry
+
+func (ts testSource) TapLog() (<-chan LogEntry, func()) {
+	return ts, func() {}
+}
+
+func TestSubscribe(t *testing.T) {
+	source := make(testSource, 4)
+	events, done := Subscribe(source, TellType, ZoneChangeType)
+	defer done()
+	source <- LogEntry{Character: "Bidbot", Message: "Alice says, 'hail'"}
+	source <- LogEntry{Character: "Bidbot", Message: "Alice tells you, '10'"}
+	source <- LogEntry{Character: "Bidbot", Message: "You have entered The Plane of Sky."}
+	for _, expected := range []Type{TellType, ZoneChangeType} {
+		select {
+		case event := <-events:
+			if event.Type() != expected {
+				t.Fatalf("Expected %v, got %v", expected, event.Type())
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("Timed out waiting for %v", expected)
+		}
+	}
+}
+
+func TestReplay(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "eqlog_Bidbot_xegony.txt")
+	recorded := "[Sat Oct 17 20:15:03 2026] Alice tells you, '10'\r\n" +
+		"not a log line\r\n" +
+		"[Sat Oct 17 20:15:05 2026] Bob tells you, '20'\r\n" +
+		"[Sat Oct 17 20:15:05 2026] Alice tells you, '30'\r\n"
+	if err := ioutil.WriteFile(filename, []byte(recorded), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// Two seconds of log at 100 times the speed takes 20ms
+	start := time.Now()
+	entries, err := Replay(context.Background(), filename, 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var texts []string
+	for entry := range entries {
+		if entry.Character != "Bidbot" || entry.Server != "xegony" {
+			t.Fatalf("Unexpected log owner %v %v", entry.Character, entry.Server)
+		}
+		texts = append(texts, entry.Message)
+	}
+	if !reflect.DeepEqual(texts, []string{"Alice tells you, '10'", "Bob tells you, '20'", "Alice tells you, '30'"}) {
+		t.Fatalf("Unexpected entries %v", texts)
+	}
+	if elapsed := time.Since(start); elapsed < 20*time.Millisecond || elapsed > 2*time.Second {
+		t.Fatalf("Expected the replay to take about 20ms, took %v", elapsed)
+	}
+
+	if _, err := Replay(context.Background(), filepath.Join(filepath.Dir(filename), "notes.txt"), 1); err == nil {
+		t.Fatal("Expected an error replaying a file which isn't a log")
+	}
+}
@@ -0,0 +1,90 @@
+package events
+
+// logfile.go: Read EverQuest log files, including replaying a recorded one.
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+)
+
+var (
+	filenameRE = regexp.MustCompile("^eqlog_([A-Za-z]*)_([A-Za-z]*).txt$")
+	loglineRE  = regexp.MustCompile("^\\[([^\\]]*)] (.*)$")
+)
+
+// The character and server an EverQuest log file belongs to, from its name, e.g. "eqlog_Bidbot_xegony.txt"
+func ParseLogFilename(name string) (character string, server string, ok bool) {
+	parts := filenameRE.FindStringSubmatch(name)
+	if parts == nil {
+		return "", "", false
+	}
+	return parts[1], parts[2], true
+}
+
+// Split a line of a character's log into its timestamp and message
+func ParseLogLine(character string, server string, line string) (LogEntry, bool) {
+	parts := loglineRE.FindStringSubmatch(line)
+	if parts == nil {
+		return LogEntry{}, false
+	}
+	return LogEntry{
+		Character: character,
+		Server:    server,
+		Timestamp: parts[1],
+		Message:   parts[2],
+	}, true
+}
+
+// Read a recorded log file, sending its entries as if they were being logged now.  With a speed of 1, entries are
+// spaced out as they were originally logged; 10 replays ten times as fast, and 0 as fast as they're received.  The
+// clock only starts once the first entry has been received.  The channel is closed at the end of the file.
+func Replay(ctx context.Context, filename string, speed float64) (<-chan LogEntry, error) {
+	character, server, ok := ParseLogFilename(filepath.Base(filename))
+	if !ok {
+		return nil, fmt.Errorf("%v isn't named like an EverQuest log (eqlog_<character>_<server>.txt)", filename)
+	}
+	fd, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	entries := make(chan LogEntry, 16)
+	go func() {
+		defer close(entries)
+		defer fd.Close()
+		scanner := bufio.NewScanner(fd)
+		scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+		var last time.Time
+		for scanner.Scan() {
+			entry, ok := ParseLogLine(character, server, strings.TrimRight(scanner.Text(), "\r"))
+			if !ok {
+				continue
+			}
+			if when, err := ParseTimestamp(entry.Timestamp); err == nil {
+				if speed > 0 && !last.IsZero() && when.After(last) {
+					select {
+					case <-time.After(time.Duration(float64(when.Sub(last)) / speed)):
+					case <-ctx.Done():
+						return
+					}
+				}
+				last = when
+			}
+			select {
+			case entries <- entry:
+			case <-ctx.Done():
+				return
+			}
+		}
+		if err := scanner.Err(); err != nil {
+			log.Printf("Failed replaying %v: %v", filename, err)
+		}
+	}()
+	return entries, nil
+}
@@ -0,0 +1,154 @@
+package events
+
+// parse.go: Recognize log lines and turn them into events.
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Turns the parts of a line matched by a pattern into an event, or returns nil if the line shouldn't count
+// after all
+type Builder func(meta Meta, parts []string) Event
+
+type registration struct {
+	pattern *regexp.Regexp
+	build   Builder
+}
+
+// Patterns are tried in the order they were registered
+var registry []registration
+
+// Add a kind of line to recognize.  Only call this while initializing, before any lines are parsed.
+func Register(pattern *regexp.Regexp, build Builder) {
+	registry = append(registry, registration{pattern, build})
+}
+
+// A /random is logged as two lines, naming the roller and then giving the result
+var (
+	rollerRE     = regexp.MustCompile(`^\*\*A Magic Die is rolled by ([A-Za-z]+)\.(?:\*\*)?$`)
+	rollResultRE = regexp.MustCompile(`^\*\*It could have been any number from ([0-9]+) to ([0-9]+), but this time it turned up a ([0-9]+)\.(?:\*\*)?$`)
+)
+
+func init() {
+	talk := func(pattern string, build func(meta Meta, from string, text string) Event) {
+		Register(regexp.MustCompile(pattern), func(meta Meta, parts []string) Event {
+			return build(meta, parts[1], parts[2])
+		})
+	}
+	talk(`^([A-Za-z]+) (?:tells|told) you, '(.*)'$`, func(meta Meta, from string, text string) Event {
+		return &Tell{meta, from, text}
+	})
+	Register(regexp.MustCompile(`^([A-Za-z]+) tells ([^ :,']+):([0-9]+), '(.*)'$`), func(meta Meta, parts []string) Event {
+		number, _ := strconv.Atoi(parts[3])
+		return &ChannelMessage{meta, parts[1], parts[2], number, parts[4]}
+	})
+	talk(`^([A-Za-z]+) tells the guild, '(.*)'$`, func(meta Meta, from string, text string) Event {
+		return &GuildSay{meta, from, text}
+	})
+	talk(`^([A-Za-z]+) tells the raid,\s+'(.*)'$`, func(meta Meta, from string, text string) Event {
+		return &RaidSay{meta, from, text}
+	})
+	talk(`^([A-Za-z]+) tells the group, '(.*)'$`, func(meta Meta, from string, text string) Event {
+		return &GroupSay{meta, from, text}
+	})
+	talk(`^([A-Za-z]+) says, '(.*)'$`, func(meta Meta, from string, text string) Event {
+		return &Say{meta, from, text}
+	})
+	talk(`^([A-Za-z]+) shouts, '(.*)'$`, func(meta Meta, from string, text string) Event {
+		return &Shout{meta, from, text}
+	})
+	talk(`^([A-Za-z]+) says out of character, '(.*)'$`, func(meta Meta, from string, text string) Event {
+		return &OOC{meta, from, text}
+	})
+	talk(`^([A-Za-z]+) auctions, '(.*)'$`, func(meta Meta, from string, text string) Event {
+		return &Auction{meta, from, text}
+	})
+
+	Register(regexp.MustCompile(`^--([A-Za-z]+) (?:has|have) looted (?:an? |([0-9]+) )?(.+?)\.--$`), func(meta Meta, parts []string) Event {
+		looter := parts[1]
+		if strings.EqualFold(looter, "you") {
+			looter = meta.Character
+		}
+		count := 1
+		if parts[2] != "" {
+			count, _ = strconv.Atoi(parts[2])
+		}
+		return &Loot{meta, looter, parts[3], count}
+	})
+	Register(regexp.MustCompile(`^You have entered (.+)\.$`), func(meta Meta, parts []string) Event {
+		// Entering special areas within a zone is logged the same way
+		lower := strings.ToLower(parts[1])
+		if strings.HasPrefix(lower, "an area") || strings.HasPrefix(lower, "an arena") {
+			return nil
+		}
+		return &ZoneChange{meta, parts[1]}
+	})
+	Register(regexp.MustCompile(`^(.+) has been slain by (.+)!$`), func(meta Meta, parts []string) Event {
+		return &Slain{meta, parts[1], parts[2]}
+	})
+	Register(regexp.MustCompile(`^You have slain (.+)!$`), func(meta Meta, parts []string) Event {
+		return &Slain{meta, parts[1], meta.Character}
+	})
+
+	Register(regexp.MustCompile(`^Outputfile Complete: (.+)$`), func(meta Meta, parts []string) Event {
+		return &OutputFileComplete{meta, parts[1]}
+	})
+	Register(regexp.MustCompile(`^([A-Za-z]+) is not online at this time\.$`), func(meta Meta, parts []string) Event {
+		return &NotOnline{meta, parts[1]}
+	})
+	Register(regexp.MustCompile(`^Channels: (.*)$`), func(meta Meta, parts []string) Event {
+		return &ChannelList{meta, parts[1]}
+	})
+	Register(regexp.MustCompile(`^You are not on any channels$`), func(meta Meta, parts []string) Event {
+		return &NoChannels{meta}
+	})
+	Register(regexp.MustCompile(`^Incorrect password for channel (.*?)\.?$`), func(meta Meta, parts []string) Event {
+		return &BadChannelPassword{meta, parts[1]}
+	})
+}
+
+// Parses the lines of one or more logs.  Rolls span two lines, so a Parser remembers the first line of a roll
+// from each log until the second comes along; it shouldn't be shared between goroutines.
+type Parser struct {
+	pendingRolls map[string]string // Roller named in the latest die line, keyed by the character whose log it's in
+}
+
+func NewParser() *Parser {
+	return &Parser{pendingRolls: make(map[string]string)}
+}
+
+// The event a log line records, or nil if it's not a line we recognize (or the first line of a roll)
+func (p *Parser) Parse(entry LogEntry) Event {
+	meta := Meta{LogEntry: entry}
+	meta.Time, _ = ParseTimestamp(entry.Timestamp)
+
+	if parts := rollerRE.FindStringSubmatch(entry.Message); parts != nil {
+		p.pendingRolls[entry.Character] = parts[1]
+		return nil
+	}
+	if parts := rollResultRE.FindStringSubmatch(entry.Message); parts != nil {
+		roller, ok := p.pendingRolls[entry.Character]
+		if !ok {
+			return nil
+		}
+		delete(p.pendingRolls, entry.Character)
+		low, _ := strconv.Atoi(parts[1])
+		high, _ := strconv.Atoi(parts[2])
+		value, _ := strconv.Atoi(parts[3])
+		return &Roll{meta, roller, low, high, value}
+	}
+
+	for _, reg := range registry {
+		if parts := reg.pattern.FindStringSubmatch(entry.Message); parts != nil {
+			return reg.build(meta, parts)
+		}
+	}
+	return nil
+}
+
+// The event a single log line records, or nil.  Rolls need a Parser, as they span two lines.
+func Parse(entry LogEntry) Event {
+	return NewParser().Parse(entry)
+}
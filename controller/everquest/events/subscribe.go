@@ -0,0 +1,46 @@
+package events
+
+// subscribe.go: Receive events of particular types as they're logged.
+
+import "sync"
+
+// Something logs are read from, e.g. an *everquest.Client
+type Source interface {
+	TapLog() (messages <-chan LogEntry, done func())
+}
+
+// Receive events of the given types (or of every type, if none are given) as they're logged from now on.
+// When events are no longer required, call `done`.
+func Subscribe(source Source, types ...Type) (events <-chan Event, done func()) {
+	wanted := make(map[Type]bool)
+	for _, t := range types {
+		wanted[t] = true
+	}
+	tap, tapDone := source.TapLog()
+	eventChan := make(chan Event, 64)
+	stop := make(chan struct{})
+	go func() {
+		defer tapDone()
+		parser := NewParser()
+		for {
+			select {
+			case <-stop:
+				return
+			case entry := <-tap:
+				event := parser.Parse(entry)
+				if event == nil || (len(wanted) != 0 && !wanted[event.Type()]) {
+					continue
+				}
+				select {
+				case eventChan <- event:
+				case <-stop:
+					return
+				}
+			}
+		}
+	}()
+	var once sync.Once
+	return eventChan, func() {
+		once.Do(func() { close(stop) })
+	}
+}
@@ -128,14 +128,14 @@ func (eqc *Client) clickLink(msgText string) error {
 // Find the most recent C&C window message, click the link in it, wait for the link window to
 // come up, then return a function which can be used to click on the icon in the link window.
 func (eqc *Client) RaiseLink(msgText string) (linkClick func(EqInput), err error) {
-	if !eqc.Config.UseLinks() {
+	if !eqc.Config.UseLinks() || eqc.Replaying() {
 		var bt string
 		bt, err = braceText(msgText)
 		if err != nil {
 			return
 		}
 		linkClick = func(eqi EqInput) {
-			typewrite(bt)
+			eqi.typewrite(bt)
 		}
 		return
 	}
@@ -171,3 +171,10 @@ func (eqc *Client) RaiseLink(msgText string) (linkClick func(EqInput), err error
 	err = errors.New("Never saw link box, was there really link text there?")
 	return
 }
+
+// Produce a "link" which just types the name of the item, for use when there's no link on screen to click.
+func PlainLink(itemName string) func(EqInput) {
+	return func(eqi EqInput) {
+		eqi.typewrite(itemName)
+	}
+}
@@ -1,112 +1,76 @@
 package everquest
 
-// logread.go: Watch for old and new EverQuest log files, read and parse them.
+// logread.go: Follow old and new EverQuest log files, and parse their lines.
 
 import (
-	"bytes"
 	"context"
-	"io"
-	"io/ioutil"
-	"os"
-	"regexp"
+	"github.com/gontikr99/bidbot2/controller/everquest/events"
+	"github.com/gontikr99/bidbot2/controller/logfollow"
+	"github.com/gontikr99/bidbot2/controller/storage"
+	"log"
 	"strings"
-	"time"
 )
 
-type EqLogEntry struct {
-	Character string
-	Server    string
-	Timestamp string
-	Message   string
-}
+type EqLogEntry = events.LogEntry
 
-var (
-	filenameMatch = regexp.MustCompile("^eqlog_([A-Za-z]*)_([A-Za-z]*).txt$")
-	loglineMatch  = regexp.MustCompile("^\\[([^\\]]*)] (.*)$")
-)
+// Which log files to read: the bot character's, and those of the characters listed in the "other characters
+// whose logs to read" setting, on the bot character's server.
+func followedLogs(config storage.ControllerConfig) func(name string) bool {
+	botName, botServer := storage.BotCharacter(config)
+	characters := map[string]bool{strings.ToLower(botName): true}
+	for _, extra := range config.ExtraLogCharacters() {
+		characters[strings.ToLower(extra)] = true
+	}
+	return func(name string) bool {
+		character, server, ok := events.ParseLogFilename(name)
+		return ok && characters[strings.ToLower(character)] && strings.EqualFold(server, botServer)
+	}
+}
 
-func readAllLogs(ctx context.Context, directory string) (<-chan EqLogEntry, error) {
-	_, err := ioutil.ReadDir(directory)
+func readAllLogs(ctx context.Context, directory string, match func(name string) bool) (<-chan EqLogEntry, error) {
+	follower := logfollow.New(directory, match)
+	follower.OnError = func(err error) {
+		log.Printf("Log reading: %v", err)
+	}
+	lines, err := follower.Start(ctx)
 	if err != nil {
 		return nil, err
 	}
 	receiver := make(chan EqLogEntry, 16)
-	go readAllLogsLoop(ctx, directory, receiver)
+	go parseLogLines(ctx, lines, receiver)
 	return receiver, nil
 }
 
-func readAllLogsLoop(ctx context.Context, directory string, receiver chan<- EqLogEntry) {
-	seen := make(map[string]bool)
-	for {
-		entries, err := ioutil.ReadDir(directory)
-		if err == nil {
-			for _, fi := range entries {
-				if _, ok := seen[fi.Name()]; !ok {
-					seen[fi.Name()] = true
-					if parts := filenameMatch.FindStringSubmatch(fi.Name()); parts != nil {
-						go tailLog(ctx, directory+"/"+fi.Name(), parts[1], parts[2], receiver)
-					}
-				}
-			}
+// Turn lines read from log files into log entries, until the follower stops
+func parseLogLines(ctx context.Context, lines <-chan logfollow.Line, receiver chan<- EqLogEntry) {
+	for line := range lines {
+		character, server, ok := events.ParseLogFilename(line.File)
+		if !ok {
+			continue
 		}
-		select {
-		case <-ctx.Done():
-			return
-		case <-time.After(200 * time.Millisecond):
-		}
-	}
-}
-
-func tailLog(ctx context.Context, filename string, character string, server string, receiver chan<- EqLogEntry) {
-	fd, err := os.OpenFile(filename, os.O_RDONLY, 0)
-	if err != nil {
-		panic(err)
-	}
-	fd.Seek(-1, io.SeekEnd)
-	rdbuf := make([]byte, 1024)
-	buffer := make([]byte, 0)
-	for {
-		cnt, _ := fd.Read(rdbuf)
-		if cnt <= 0 {
+		if entry, ok := events.ParseLogLine(character, server, line.Text); ok {
 			select {
+			case receiver <- entry:
 			case <-ctx.Done():
 				return
-			default:
-				time.Sleep(5 * time.Millisecond)
-				continue
 			}
 		}
-		buffer = append(buffer, rdbuf[0:cnt]...)
-		ib := bytes.IndexByte(buffer, '\n')
-		if ib >= 0 {
-			buffer = append(make([]byte, 0), buffer[ib+1:]...)
-			break
-		}
 	}
+}
 
-	for {
-		for ib := bytes.IndexByte(buffer, '\n'); ib >= 0; ib = bytes.IndexByte(buffer, '\n') {
-			line := strings.ReplaceAll(string(buffer[0:ib-1]), "\r", "")
-			buffer = append(make([]byte, 0), buffer[ib+1:]...)
-			if parts := loglineMatch.FindStringSubmatch(line); parts != nil {
-				receiver <- EqLogEntry{
-					Character: character,
-					Server:    server,
-					Timestamp: parts[1],
-					Message:   parts[2],
-				}
-			}
-		}
-		cnt, _ := fd.Read(rdbuf)
-		if cnt <= 0 {
-			select {
-			case <-ctx.Done():
-				return
-			default:
-				time.Sleep(5 * time.Millisecond)
-				continue
-			}
-		}
-		buffer = append(buffer, rdbuf[0:cnt]...)
-	}
+// Whether a log entry came from the bot character's own log, rather than from another character's log read
+// alongside it.  Tells and channel messages meant for the bot only count when they're in its own log.
+func (eqc *Client) IsBotLog(msg EqLogEntry) bool {
+	return strings.EqualFold(msg.Character, eqc.botName)
+}
+
+// The character the bot plays, whose log has its tells and channel messages
+func (eqc *Client) BotName() string {
+	return eqc.botName
+}
+
+// Receive events of the given types (or of every type) from the logs as they're read.  When events are no longer
+// required, call `done`.
+func (eqc *Client) Subscribe(types ...events.Type) (eventChan <-chan events.Event, done func()) {
+	return events.Subscribe(eqc, types...)
 }
@@ -0,0 +1,62 @@
+package everquest
+
+// replay.go: Run the bot against a recorded log instead of a live EverQuest, writing what it would have typed
+// into EverQuest to a transcript.
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/gontikr99/bidbot2/controller/everquest/events"
+	"github.com/gontikr99/bidbot2/controller/storage"
+	"io"
+	"log"
+	"path/filepath"
+)
+
+var errReplaying = errors.New("EverQuest isn't available while replaying a log")
+
+// Create a client which replays a recorded log, e.g. "eqlog_Bidbot_xegony.txt", at the given speed (see
+// events.Replay).  The log's character is taken to be the bot character.  Nothing is typed into EverQuest; what
+// would have been is written to `transcript` instead.  Call StartReplay once everything watching the log is set up.
+func NewReplayClient(ctx context.Context, config storage.ControllerConfig, logFile string, speed float64,
+	transcript io.Writer) (client *Client, err error) {
+	client = &Client{}
+	client.Config = config
+	client.Context = ctx
+	client.transcript = transcript
+	client.botName, _, _ = events.ParseLogFilename(filepath.Base(logFile))
+	client.logChan, err = events.Replay(ctx, logFile, speed)
+	if err != nil {
+		return
+	}
+	client.logTaps = make(map[int]*tapPair)
+	return
+}
+
+// Start feeding the recorded log to whatever's tapped it
+func (eqc *Client) StartReplay() {
+	eqc.replayStart.Do(func() {
+		log.Println("Replaying log")
+		go eqc.forwardLogMessages()
+	})
+}
+
+// Whether this client is replaying a recorded log rather than driving EverQuest
+func (eqc *Client) Replaying() bool {
+	return eqc.transcript != nil
+}
+
+// Record something that would have been done in EverQuest or Discord, stamped with the time of the latest replayed
+// log entry
+func (eqc *Client) Transcribe(format string, args ...interface{}) {
+	eqc.logSync.Lock()
+	timestamp := eqc.logTimestamp
+	eqc.logSync.Unlock()
+	eqc.transcriptSync.Lock()
+	defer eqc.transcriptSync.Unlock()
+	_, err := fmt.Fprintf(eqc.transcript, "[%v] %v\r\n", timestamp, fmt.Sprintf(format, args...))
+	if err != nil {
+		log.Printf("Failed to write transcript: %v", err)
+	}
+}
@@ -2,19 +2,13 @@ package everquest
 
 import (
 	"errors"
+	"github.com/gontikr99/bidbot2/controller/everquest/events"
 	"log"
-	"regexp"
 	"time"
 )
 
-var (
-	chanRE   = regexp.MustCompile("^Channels: .*$")
-	noChanRE = regexp.MustCompile("^You are not on any channels$")
-	badPWRE  = regexp.MustCompile("^Incorrect password for channel .*$")
-)
-
 func leaveChannels(eqc *Client) error {
-	tap, done := eqc.TapLog()
+	tap, done := eqc.Subscribe(events.ChannelListType, events.NoChannelsType)
 	defer done()
 	for {
 		err := eqc.Send("/leave 1")
@@ -24,13 +18,14 @@ func leaveChannels(eqc *Client) error {
 		timeout := time.After(5 * time.Second)
 		for readyForLeave := false; !readyForLeave; {
 			select {
-			case msg := <-tap:
-				if noChanRE.MatchString(msg.Message) {
-					return nil
+			case event := <-tap:
+				if !eqc.IsBotLog(event.Logged().LogEntry) {
+					continue
 				}
-				if chanRE.MatchString(msg.Message) {
-					readyForLeave = true
+				if event.Type() == events.NoChannelsType {
+					return nil
 				}
+				readyForLeave = true
 			case <-timeout:
 				return errors.New("Failed to leave a channel")
 			}
@@ -39,7 +34,7 @@ func leaveChannels(eqc *Client) error {
 }
 
 func joinChannel(eqc *Client, chantext string) error {
-	tap, done := eqc.TapLog()
+	tap, done := eqc.Subscribe(events.BadChannelPasswordType)
 	defer done()
 	err := eqc.Send("/join " + chantext)
 	if err != nil {
@@ -48,8 +43,8 @@ func joinChannel(eqc *Client, chantext string) error {
 	timeout := time.After(3 * time.Second)
 	for {
 		select {
-		case msg := <-tap:
-			if badPWRE.MatchString(msg.Message) {
+		case event := <-tap:
+			if eqc.IsBotLog(event.Logged().LogEntry) {
 				return errors.New("Bad password")
 			}
 		case <-timeout:
@@ -59,7 +54,7 @@ func joinChannel(eqc *Client, chantext string) error {
 }
 
 func checkChannels(eqc *Client) error {
-	tap, done := eqc.TapLog()
+	tap, done := eqc.Subscribe(events.ChannelListType, events.NoChannelsType)
 	defer done()
 	err := eqc.Send("/list")
 	if err != nil {
@@ -68,13 +63,14 @@ func checkChannels(eqc *Client) error {
 	timeout := time.After(5 * time.Second)
 	for {
 		select {
-		case msg := <-tap:
-			if noChanRE.MatchString(msg.Message) {
-				return errors.New("Not in a channel")
+		case event := <-tap:
+			if !eqc.IsBotLog(event.Logged().LogEntry) {
+				continue
 			}
-			if chanRE.MatchString(msg.Message) {
-				return nil
+			if event.Type() == events.NoChannelsType {
+				return errors.New("Not in a channel")
 			}
+			return nil
 		case <-timeout:
 			return errors.New("Never saw channel listing")
 		}
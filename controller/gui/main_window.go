@@ -3,6 +3,7 @@ package gui
 import (
 	"context"
 	"encoding/json"
+	"fmt"
 	"github.com/gontikr99/bidbot2/controller/everquest"
 	storage2 "github.com/gontikr99/bidbot2/controller/storage"
 	"github.com/lxn/walk"
@@ -12,6 +13,7 @@ import (
 	"os"
 	"regexp"
 	"sort"
+	"strconv"
 	"strings"
 )
 
@@ -100,6 +102,15 @@ func validLua(filename string) bool {
 	return err == nil
 }
 
+// The item data file is optional, but has to exist if given
+func validItemData(filename string) bool {
+	if filename == "" {
+		return true
+	}
+	_, err := os.Stat(filename)
+	return err == nil
+}
+
 func enumerateCharacters(directory string) *CharModel {
 	cm := NewCharModel()
 	files, err := ioutil.ReadDir(directory)
@@ -132,6 +143,23 @@ type mainWindowModel struct {
 	luaEdit   *walk.LineEdit
 	luaBrowse *walk.PushButton
 
+	itemsEdit   *walk.LineEdit
+	itemsBrowse *walk.PushButton
+
+	timelineEdit *walk.LineEdit
+	snipeEdit    *walk.LineEdit
+	openEdit     *walk.LineEdit
+	raidOnly     *walk.CheckBox
+
+	decayEdit *walk.LineEdit
+	capsEdit  *walk.LineEdit
+	zeroSum   *walk.CheckBox
+
+	lootersEdit   *walk.LineEdit
+	bossesEdit    *walk.LineEdit
+	bossDKPEdit   *walk.LineEdit
+	extraLogsEdit *walk.LineEdit
+
 	prepareButton *walk.PushButton
 	startButton   *walk.PushButton
 	started       bool
@@ -149,6 +177,19 @@ func (mwm *mainWindowModel) shade() {
 		mwm.credBrowse.SetEnabled(false)
 		mwm.luaEdit.SetEnabled(false)
 		mwm.luaBrowse.SetEnabled(false)
+		mwm.itemsEdit.SetEnabled(false)
+		mwm.itemsBrowse.SetEnabled(false)
+		mwm.timelineEdit.SetEnabled(false)
+		mwm.snipeEdit.SetEnabled(false)
+		mwm.openEdit.SetEnabled(false)
+		mwm.raidOnly.SetEnabled(false)
+		mwm.decayEdit.SetEnabled(false)
+		mwm.capsEdit.SetEnabled(false)
+		mwm.zeroSum.SetEnabled(false)
+		mwm.lootersEdit.SetEnabled(false)
+		mwm.bossesEdit.SetEnabled(false)
+		mwm.bossDKPEdit.SetEnabled(false)
+		mwm.extraLogsEdit.SetEnabled(false)
 		mwm.prepareButton.SetEnabled(false)
 		mwm.useLinks.SetEnabled(false)
 		mwm.startButton.SetEnabled(true)
@@ -163,6 +204,19 @@ func (mwm *mainWindowModel) shade() {
 		mwm.credBrowse.SetEnabled(true)
 		mwm.luaEdit.SetEnabled(true)
 		mwm.luaBrowse.SetEnabled(true)
+		mwm.itemsEdit.SetEnabled(true)
+		mwm.itemsBrowse.SetEnabled(true)
+		mwm.timelineEdit.SetEnabled(true)
+		mwm.snipeEdit.SetEnabled(true)
+		mwm.openEdit.SetEnabled(true)
+		mwm.raidOnly.SetEnabled(true)
+		mwm.decayEdit.SetEnabled(true)
+		mwm.capsEdit.SetEnabled(true)
+		mwm.zeroSum.SetEnabled(true)
+		mwm.lootersEdit.SetEnabled(true)
+		mwm.bossesEdit.SetEnabled(true)
+		mwm.bossDKPEdit.SetEnabled(true)
+		mwm.extraLogsEdit.SetEnabled(true)
 		mwm.useLinks.SetEnabled(true)
 		mwm.announceChan.SetEnabled(true)
 	}
@@ -187,19 +241,67 @@ func (mwm *mainWindowModel) shade() {
 	validToken := validToken(mwm.tokenEdit.Text())
 	validCred := validCred(mwm.credEdit.Text())
 	validLua := validLua(mwm.luaEdit.Text())
+	validItems := validItemData(mwm.itemsEdit.Text())
+	_, schedErr := storage2.ParseAuctionSchedule(mwm.timelineEdit.Text(), mwm.snipeEdit.Text(), mwm.openEdit.Text())
+	validSchedule := schedErr == nil
+	_, policyErr := storage2.ParseDKPPolicy(mwm.decayEdit.Text(), mwm.capsEdit.Text(), mwm.zeroSum.Checked())
+	validPolicy := policyErr == nil
+	_, bossDKPErr := parseBossDKP(mwm.bossDKPEdit.Text())
+	validBossDKP := bossDKPErr == nil
 
 	if !useLinks {
-		mwm.charBox.SetEnabled(false)
 		mwm.prepareButton.SetEnabled(false)
 	}
 
-	if validDir && (!useLinks || charSelected) && validChannel && validToken && validCred && validLua {
+	// Only the bot character's log is read, so it has to be chosen even without item linking
+	if validDir && charSelected && validChannel && validToken && validCred && validLua && validItems && validSchedule && validPolicy && validBossDKP {
 		mwm.startButton.SetEnabled(true)
 	} else {
 		mwm.startButton.SetEnabled(false)
 	}
 }
 
+// Save the auction schedule whenever its settings are changed to something valid
+func (mwm *mainWindowModel) scheduleChanged(config storage2.ControllerConfig) func() {
+	return func() {
+		if mwm.timelineEdit == nil || mwm.snipeEdit == nil || mwm.openEdit == nil {
+			return
+		}
+		schedule, err := storage2.ParseAuctionSchedule(mwm.timelineEdit.Text(), mwm.snipeEdit.Text(), mwm.openEdit.Text())
+		if err == nil {
+			config.SetAuctionSchedule(schedule)
+		}
+		mwm.shade()
+	}
+}
+
+// Parse the DKP given out per boss kill, where blank means none
+func parseBossDKP(text string) (float64, error) {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return 0, nil
+	}
+	value, err := strconv.ParseFloat(text, 64)
+	if err != nil || value < 0 {
+		return 0, fmt.Errorf("'%v' isn't an amount of DKP", text)
+	}
+	return value, nil
+}
+
+// Save the DKP policies whenever their settings are changed to something valid
+func (mwm *mainWindowModel) policyChanged(config storage2.ControllerConfig) func() {
+	return func() {
+		if mwm.decayEdit == nil || mwm.capsEdit == nil || mwm.zeroSum == nil {
+			return
+		}
+		policy, err := storage2.ParseDKPPolicy(mwm.decayEdit.Text(), mwm.capsEdit.Text(), mwm.zeroSum.Checked())
+		if err == nil {
+			config.SetDKPPolicy(policy)
+		}
+		mwm.shade()
+	}
+}
+
 func RunMainWindow(config storage2.ControllerConfig, start func(context.Context, storage2.ControllerConfig)) {
 	model := &mainWindowModel{}
 	var doneFunc func()
@@ -420,6 +522,152 @@ func RunMainWindow(config storage2.ControllerConfig, start func(context.Context,
 							}
 						},
 					},
+					Label{
+						Text:          "Item data (optional)",
+						TextAlignment: AlignFar,
+					},
+					LineEdit{
+						AssignTo: &model.itemsEdit,
+						OnTextChanged: func() {
+							config.SetItemData(model.itemsEdit.Text())
+							model.shade()
+						},
+					},
+					PushButton{
+						Text:     "Browse...",
+						AssignTo: &model.itemsBrowse,
+						OnClicked: func() {
+							dialog := &walk.FileDialog{
+								Title:    "Select item data file",
+								FilePath: model.itemsEdit.Text(),
+								Filter:   "Item data (*.csv;*.json)",
+							}
+							choose, err := dialog.ShowOpen(model.mainWindow)
+							if err != nil {
+								log.Printf("Failed to show file dialog: %v", err)
+								return
+							}
+							if choose {
+								model.itemsEdit.SetText(dialog.FilePath)
+							}
+						},
+					},
+					Label{
+						Text:          "Auction length, then warnings (seconds)",
+						TextAlignment: AlignFar,
+					},
+					LineEdit{
+						AssignTo:      &model.timelineEdit,
+						ColumnSpan:    2,
+						OnTextChanged: model.scheduleChanged(config),
+					},
+					Label{
+						Text:          "Anti-snipe window/extension (seconds)",
+						TextAlignment: AlignFar,
+					},
+					LineEdit{
+						AssignTo:      &model.snipeEdit,
+						ColumnSpan:    2,
+						OnTextChanged: model.scheduleChanged(config),
+					},
+					Label{
+						Text:          "Open auction idle close/minimum raise",
+						TextAlignment: AlignFar,
+					},
+					LineEdit{
+						AssignTo:      &model.openEdit,
+						ColumnSpan:    2,
+						OnTextChanged: model.scheduleChanged(config),
+					},
+					Label{
+						Text:          "Only raid members (and standby) may bid",
+						TextAlignment: AlignFar,
+					},
+					CheckBox{
+						AssignTo:   &model.raidOnly,
+						ColumnSpan: 2,
+						OnCheckedChanged: func() {
+							config.SetRaidOnlyBids(model.raidOnly.Checked())
+						},
+					},
+					Label{
+						Text:          "DKP decay percent/every days (optional)",
+						TextAlignment: AlignFar,
+					},
+					LineEdit{
+						AssignTo:      &model.decayEdit,
+						ColumnSpan:    2,
+						OnTextChanged: model.policyChanged(config),
+					},
+					Label{
+						Text:          "DKP minimum/maximum balance (optional)",
+						TextAlignment: AlignFar,
+					},
+					LineEdit{
+						AssignTo:      &model.capsEdit,
+						ColumnSpan:    2,
+						OnTextChanged: model.policyChanged(config),
+					},
+					Label{
+						Text:          "Zero-sum DKP (share spent DKP with the raid)",
+						TextAlignment: AlignFar,
+					},
+					CheckBox{
+						AssignTo:         &model.zeroSum,
+						ColumnSpan:       2,
+						OnCheckedChanged: model.policyChanged(config),
+					},
+					Label{
+						Text:          "Looters to watch for loot (optional)",
+						TextAlignment: AlignFar,
+					},
+					LineEdit{
+						AssignTo:   &model.lootersEdit,
+						ColumnSpan: 2,
+						OnTextChanged: func() {
+							config.SetLooters(strings.FieldsFunc(model.lootersEdit.Text(), func(r rune) bool {
+								return r == ' ' || r == ','
+							}))
+						},
+					},
+					Label{
+						Text:          "Other characters whose logs to read (optional)",
+						TextAlignment: AlignFar,
+					},
+					LineEdit{
+						AssignTo:   &model.extraLogsEdit,
+						ColumnSpan: 2,
+						OnTextChanged: func() {
+							config.SetExtraLogCharacters(strings.FieldsFunc(model.extraLogsEdit.Text(), func(r rune) bool {
+								return r == ' ' || r == ','
+							}))
+						},
+					},
+					Label{
+						Text:          "Bosses, separated by commas (optional)",
+						TextAlignment: AlignFar,
+					},
+					LineEdit{
+						AssignTo:   &model.bossesEdit,
+						ColumnSpan: 2,
+						OnTextChanged: func() {
+							config.SetBosses(strings.Split(model.bossesEdit.Text(), ","))
+						},
+					},
+					Label{
+						Text:          "DKP per boss kill (optional)",
+						TextAlignment: AlignFar,
+					},
+					LineEdit{
+						AssignTo:   &model.bossDKPEdit,
+						ColumnSpan: 2,
+						OnTextChanged: func() {
+							if value, err := parseBossDKP(model.bossDKPEdit.Text()); err == nil {
+								config.SetBossKillDKP(value)
+							}
+							model.shade()
+						},
+					},
 				},
 			},
 			HSplitter{
@@ -474,7 +722,23 @@ func RunMainWindow(config storage2.ControllerConfig, start func(context.Context,
 	model.tokenEdit.SetText(config.DiscordToken())
 	model.credEdit.SetText(config.CloudTTSCredPath())
 	model.luaEdit.SetText(config.RulesLua())
+	model.itemsEdit.SetText(config.ItemData())
 	model.useLinks.SetChecked(config.UseLinks())
+	model.raidOnly.SetChecked(config.RaidOnlyBids())
+	schedule := config.AuctionSchedule()
+	model.timelineEdit.SetText(schedule.TimelineText())
+	model.snipeEdit.SetText(schedule.SnipeText())
+	model.openEdit.SetText(schedule.OpenText())
+	policy := config.DKPPolicy()
+	model.decayEdit.SetText(policy.DecayText())
+	model.capsEdit.SetText(policy.CapsText())
+	model.zeroSum.SetChecked(policy.ZeroSum)
+	model.lootersEdit.SetText(strings.Join(config.Looters(), " "))
+	model.extraLogsEdit.SetText(strings.Join(config.ExtraLogCharacters(), " "))
+	model.bossesEdit.SetText(strings.Join(config.Bosses(), ", "))
+	if bossDKP := config.BossKillDKP(); bossDKP != 0 {
+		model.bossDKPEdit.SetText(strconv.FormatFloat(bossDKP, 'f', -1, 64))
+	}
 	curAnnounceChan := config.AnnounceChannel()
 	for idx, ac := range announceChannels.items {
 		if ac.ChanCmd == curAnnounceChan {
@@ -0,0 +1,311 @@
+package items
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// What we know about an item, as imported from the item data file
+type Item struct {
+	ID      int      `json:"id"`
+	Name    string   `json:"name"`
+	Slots   []string `json:"slots"`
+	Classes []string `json:"classes"` // Three letter abbreviations, e.g. "WAR", or "ALL"
+	Races   []string `json:"races"`   // Three letter abbreviations, e.g. "HUM", or "ALL"
+	Lore    bool     `json:"lore"`
+	NoDrop  bool     `json:"nodrop"`
+}
+
+// Full class names, as they appear in guild dumps, and their abbreviations
+var classAbbreviations = map[string]string{
+	"bard":          "BRD",
+	"beastlord":     "BST",
+	"berserker":     "BER",
+	"cleric":        "CLR",
+	"druid":         "DRU",
+	"enchanter":     "ENC",
+	"magician":      "MAG",
+	"monk":          "MNK",
+	"necromancer":   "NEC",
+	"paladin":       "PAL",
+	"ranger":        "RNG",
+	"rogue":         "ROG",
+	"shadow knight": "SHD",
+	"shaman":        "SHM",
+	"warrior":       "WAR",
+	"wizard":        "WIZ",
+}
+
+// Turn a class name or abbreviation into an abbreviation, e.g. "Shadow Knight" into "SHD"
+func ClassAbbreviation(class string) string {
+	class = strings.TrimSpace(class)
+	if abbr, ok := classAbbreviations[strings.ToLower(class)]; ok {
+		return abbr
+	}
+	return strings.ToUpper(class)
+}
+
+// Check whether a character of the given class (full name or abbreviation) can use the item.  Items
+// without class information are assumed to be usable by everyone.
+func (item *Item) UsableBy(class string) bool {
+	if len(item.Classes) == 0 {
+		return true
+	}
+	abbr := ClassAbbreviation(class)
+	for _, itemClass := range item.Classes {
+		itemClass = strings.ToUpper(itemClass)
+		if itemClass == "ALL" || itemClass == abbr {
+			return true
+		}
+	}
+	return false
+}
+
+// A one line summary of the item, e.g. "Slots: Primary Secondary, Classes: WAR PAL, LORE"
+func (item *Item) Summary() string {
+	parts := make([]string, 0, 4)
+	if len(item.Slots) != 0 {
+		parts = append(parts, "Slots: "+strings.Join(item.Slots, " "))
+	}
+	if len(item.Classes) != 0 {
+		parts = append(parts, "Classes: "+strings.Join(item.Classes, " "))
+	}
+	if item.Lore {
+		parts = append(parts, "LORE")
+	}
+	if item.NoDrop {
+		parts = append(parts, "NO DROP")
+	}
+	return strings.Join(parts, ", ")
+}
+
+// A set of items which can be looked up by name
+type Database struct {
+	items  []*Item
+	byName map[string]*Item
+}
+
+func newDatabase(items []*Item) *Database {
+	db := &Database{
+		items:  items,
+		byName: make(map[string]*Item),
+	}
+	for _, item := range items {
+		key := normalize(item.Name)
+		if _, present := db.byName[key]; !present {
+			db.byName[key] = item
+		}
+	}
+	return db
+}
+
+// Number of items in the database
+func (db *Database) Len() int {
+	return len(db.items)
+}
+
+// Find the item whose name best matches `name`, or nil if nothing is close.  In order of preference:
+// the same name ignoring case and punctuation, the only name starting with `name`, the only name
+// containing `name`, or the name with the fewest typos.
+func (db *Database) Find(name string) *Item {
+	key := normalize(name)
+	if key == "" {
+		return nil
+	}
+	if item, ok := db.byName[key]; ok {
+		return item
+	}
+	var prefixed, contained []*Item
+	for _, item := range db.items {
+		itemKey := normalize(item.Name)
+		if strings.HasPrefix(itemKey, key) {
+			prefixed = append(prefixed, item)
+		} else if strings.Contains(itemKey, key) {
+			contained = append(contained, item)
+		}
+	}
+	if len(prefixed) == 1 {
+		return prefixed[0]
+	}
+	if len(prefixed) == 0 && len(contained) == 1 {
+		return contained[0]
+	}
+
+	maxDistance := len(key) / 5
+	if maxDistance < 2 {
+		maxDistance = 2
+	}
+	var best *Item
+	bestDistance := maxDistance + 1
+	for _, item := range db.items {
+		distance := levenshtein(key, normalize(item.Name))
+		if distance < bestDistance {
+			best, bestDistance = item, distance
+		}
+	}
+	return best
+}
+
+// Lower case, with punctuation removed and whitespace collapsed
+func normalize(name string) string {
+	var sb strings.Builder
+	space := false
+	for _, r := range strings.ToLower(name) {
+		switch {
+		case (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9'):
+			if space && sb.Len() != 0 {
+				sb.WriteRune(' ')
+			}
+			space = false
+			sb.WriteRune(r)
+		case r == ' ' || r == '\t' || r == '-' || r == '_':
+			space = true
+		}
+	}
+	return sb.String()
+}
+
+func levenshtein(a string, b string) int {
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min3(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(b)]
+}
+
+func min3(a, b, c int) int {
+	if b < a {
+		a = b
+	}
+	if c < a {
+		a = c
+	}
+	return a
+}
+
+// Read items from a JSON list of objects with the fields of `Item`
+func ParseJSON(r io.Reader) (*Database, error) {
+	var items []*Item
+	err := json.NewDecoder(r).Decode(&items)
+	if err != nil {
+		return nil, err
+	}
+	return newDatabase(items), nil
+}
+
+// Read items from CSV, with a header row naming the columns: id, name, slots, classes, races, lore, nodrop.
+// Only name is required.  Lists are separated by spaces or '|', and flags are 1/0, true/false or yes/no.
+func ParseCSV(r io.Reader) (*Database, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	header, err := reader.Read()
+	if err != nil {
+		return nil, err
+	}
+	columns := make(map[string]int)
+	for idx, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(name))] = idx
+	}
+	if _, ok := columns["name"]; !ok {
+		return nil, errors.New("item CSV has no 'name' column")
+	}
+	field := func(row []string, name string) string {
+		idx, ok := columns[name]
+		if !ok || idx >= len(row) {
+			return ""
+		}
+		return strings.TrimSpace(row[idx])
+	}
+	list := func(text string) []string {
+		return strings.FieldsFunc(text, func(r rune) bool { return r == ' ' || r == '|' })
+	}
+	flag := func(text string) bool {
+		switch strings.ToLower(text) {
+		case "1", "true", "yes", "y":
+			return true
+		}
+		return false
+	}
+
+	items := make([]*Item, 0)
+	for line := 2; ; line++ {
+		row, err := reader.Read()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+		item := &Item{
+			Name:    field(row, "name"),
+			Slots:   list(field(row, "slots")),
+			Classes: list(field(row, "classes")),
+			Races:   list(field(row, "races")),
+			Lore:    flag(field(row, "lore")),
+			NoDrop:  flag(field(row, "nodrop")),
+		}
+		if item.Name == "" {
+			continue
+		}
+		if idText := field(row, "id"); idText != "" {
+			item.ID, err = strconv.Atoi(idText)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: '%v' isn't an item ID", line, idText)
+			}
+		}
+		items = append(items, item)
+	}
+	return newDatabase(items), nil
+}
+
+var currentSync sync.RWMutex
+var current = newDatabase(nil)
+
+// Load the item data file (.json or .csv) and make it the database used by Find
+func Load(filename string) error {
+	file, err := os.Open(filename)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	var db *Database
+	if strings.ToLower(filepath.Ext(filename)) == ".json" {
+		db, err = ParseJSON(file)
+	} else {
+		db, err = ParseCSV(file)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read %v: %v", filename, err)
+	}
+	currentSync.Lock()
+	current = db
+	currentSync.Unlock()
+	return nil
+}
+
+// Look up an item in the loaded database, or nil if it isn't known
+func Find(name string) *Item {
+	currentSync.RLock()
+	db := current
+	currentSync.RUnlock()
+	return db.Find(name)
+}
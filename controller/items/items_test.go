@@ -0,0 +1,83 @@
+package items
+
+import (
+	"strings"
+	"testing"
+)
+
+const testCSV = `id,name,slots,classes,races,lore,nodrop
+1001,Cloak of Flames,Back,ALL,ALL,0,0
+1002,Fungus Covered Scale Tunic,Chest,WAR|PAL|SHD|BRD,ALL,1,1
+1003,Shield of the Immaculate,Secondary,CLR PAL,ALL,0,1
+1004,Spell: Ice Comet,,WIZ,ALL,0,0
+`
+
+func TestParseCSV(t *testing.T) {
+	db, err := ParseCSV(strings.NewReader(testCSV))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if db.Len() != 4 {
+		t.Fatalf("Expected 4 items, got %v", db.Len())
+	}
+	tunic := db.Find("Fungus Covered Scale Tunic")
+	if tunic == nil || tunic.ID != 1002 || !tunic.Lore || !tunic.NoDrop || len(tunic.Classes) != 4 {
+		t.Fatalf("Failed to read the tunic, got %v", tunic)
+	}
+	if tunic.Summary() != "Slots: Chest, Classes: WAR PAL SHD BRD, LORE, NO DROP" {
+		t.Fatalf("Unexpected summary: %v", tunic.Summary())
+	}
+}
+
+func TestParseJSON(t *testing.T) {
+	db, err := ParseJSON(strings.NewReader(`[{"id": 7, "name": "Rusty Dagger", "slots": ["Primary"], "classes": ["ALL"]}]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if item := db.Find("rusty dagger"); item == nil || item.ID != 7 {
+		t.Fatalf("Failed to find the dagger, got %v", item)
+	}
+}
+
+func TestFind(t *testing.T) {
+	db, err := ParseCSV(strings.NewReader(testCSV))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		query string
+		id    int
+	}{
+		{"cloak of flames", 1001},
+		{"Spell Ice Comet", 1004},
+		{"Fungus", 1002},
+		{"Immaculate", 1003},
+		{"Clok of Flams", 1001},
+		{"Bracer of the Hidden", 0},
+		{"", 0},
+	}
+	for _, test := range tests {
+		item := db.Find(test.query)
+		id := 0
+		if item != nil {
+			id = item.ID
+		}
+		if id != test.id {
+			t.Errorf("Find(%q): expected %v, got %v", test.query, test.id, id)
+		}
+	}
+}
+
+func TestUsableBy(t *testing.T) {
+	db, err := ParseCSV(strings.NewReader(testCSV))
+	if err != nil {
+		t.Fatal(err)
+	}
+	shield := db.Find("Shield of the Immaculate")
+	if !shield.UsableBy("Paladin") || !shield.UsableBy("clr") || shield.UsableBy("Shadow Knight") {
+		t.Fatal("Wrong classes for the shield")
+	}
+	if !db.Find("Cloak of Flames").UsableBy("Necromancer") {
+		t.Fatal("Everyone should be able to use the cloak")
+	}
+}
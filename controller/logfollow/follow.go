@@ -0,0 +1,332 @@
+package logfollow
+
+// follow.go: Follow the files in a directory as they grow, the way `tail -F` does, coping with files
+// being truncated, renamed away and recreated, and with files nobody has written to in a long time.
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"github.com/fsnotify/fsnotify"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// A complete line appended to one of the followed files
+type Line struct {
+	File string // Base name of the file, e.g. "eqlog_Jephine_xegony.txt"
+	Text string // The line, without its line ending
+}
+
+// Follows the files of a directory which match a pattern.  Set the fields before calling Start.
+type Follower struct {
+	Directory string
+	Match     func(name string) bool // Which file names to follow
+
+	// How often to look for changes when filesystem notifications aren't available
+	PollInterval time.Duration
+	// How often to look for changes anyway when they are, in case a notification is missed
+	RescanInterval time.Duration
+	// How long a file may go without growing before its handle is closed (it's reopened when it changes)
+	IdleTimeout time.Duration
+	// Use filesystem notifications if the platform supports them
+	UseNotifications bool
+	// Called with problems reading files, which are retried later rather than given up on
+	OnError func(err error)
+}
+
+// A Follower with the usual settings
+func New(directory string, match func(name string) bool) *Follower {
+	return &Follower{
+		Directory:        directory,
+		Match:            match,
+		PollInterval:     100 * time.Millisecond,
+		RescanInterval:   time.Second,
+		IdleTimeout:      5 * time.Minute,
+		UseNotifications: true,
+		OnError:          func(error) {},
+	}
+}
+
+// Start following files.  Lines already in the files when Start is called are skipped, while files
+// created later (including those which replace a renamed file) are read from the beginning.  The returned
+// channel is closed once the context is done and every file has been closed.
+func (f *Follower) Start(ctx context.Context) (<-chan Line, error) {
+	if _, err := ioutil.ReadDir(f.Directory); err != nil {
+		return nil, err
+	}
+	var watcher *fsnotify.Watcher
+	if f.UseNotifications {
+		var err error
+		watcher, err = fsnotify.NewWatcher()
+		if err == nil {
+			err = watcher.Add(f.Directory)
+			if err != nil {
+				watcher.Close()
+				watcher = nil
+			}
+		}
+		if err != nil {
+			f.OnError(fmt.Errorf("falling back to polling %v: %w", f.Directory, err))
+		}
+	}
+	fs := &followState{
+		Follower: f,
+		files:    make(map[string]*followedFile),
+		lines:    make(chan Line, 16),
+	}
+	fs.scan(ctx, true)
+	go fs.run(ctx, watcher)
+	return fs.lines, nil
+}
+
+// What's known about the files being followed, owned by a single goroutine
+type followState struct {
+	*Follower
+	files map[string]*followedFile
+	lines chan Line
+}
+
+type followedFile struct {
+	name     string
+	fd       *os.File    // nil while idle or missing
+	info     os.FileInfo // Identity of the file being read, for noticing it's been replaced
+	offset   int64
+	partial  []byte // Incomplete last line
+	skipping bool   // Discarding the rest of a line which started before we did
+	active   time.Time
+	lastErr  string
+}
+
+func (fs *followState) run(ctx context.Context, watcher *fsnotify.Watcher) {
+	defer close(fs.lines)
+	defer fs.closeAll()
+
+	var events <-chan fsnotify.Event
+	var errs <-chan error
+	interval := fs.PollInterval
+	if watcher != nil {
+		defer watcher.Close()
+		events = watcher.Events
+		errs = watcher.Errors
+		interval = fs.RescanInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case event, ok := <-events:
+			if !ok {
+				events, errs = nil, nil
+				ticker.Reset(fs.PollInterval)
+				continue
+			}
+			name := filepath.Base(event.Name)
+			if !fs.Match(name) {
+				continue
+			}
+			file, ok := fs.files[name]
+			if !ok {
+				file = &followedFile{name: name}
+				fs.files[name] = file
+			}
+			fs.poll(ctx, file)
+		case err, ok := <-errs:
+			if !ok {
+				events, errs = nil, nil
+				ticker.Reset(fs.PollInterval)
+				continue
+			}
+			fs.OnError(fmt.Errorf("watching %v: %w", fs.Directory, err))
+		case <-ticker.C:
+			fs.scan(ctx, false)
+		}
+	}
+}
+
+// Look for new files, and read whatever's been added to all of them
+func (fs *followState) scan(ctx context.Context, initial bool) {
+	entries, err := ioutil.ReadDir(fs.Directory)
+	if err != nil {
+		fs.OnError(fmt.Errorf("listing %v: %w", fs.Directory, err))
+		return
+	}
+	for _, fi := range entries {
+		if fi.IsDir() || !fs.Match(fi.Name()) {
+			continue
+		}
+		if _, ok := fs.files[fi.Name()]; !ok {
+			file := &followedFile{name: fi.Name()}
+			if initial {
+				fs.skipExisting(file)
+			}
+			fs.files[fi.Name()] = file
+		}
+	}
+	for _, file := range fs.files {
+		fs.poll(ctx, file)
+	}
+}
+
+// Open a file which was there before we started at its end, skipping any incomplete last line
+func (fs *followState) skipExisting(file *followedFile) {
+	if !fs.open(file) {
+		return
+	}
+	size := file.info.Size()
+	file.offset = size
+	if size > 0 {
+		last := make([]byte, 1)
+		if _, err := file.fd.ReadAt(last, size-1); err == nil && last[0] != '\n' {
+			file.skipping = true
+		}
+	}
+}
+
+// Open a file, noticing whether it's a different file than the one we last read under its name.  Returns
+// false if it couldn't be opened.
+func (fs *followState) open(file *followedFile) bool {
+	fd, err := os.Open(filepath.Join(fs.Directory, file.name))
+	if os.IsNotExist(err) {
+		return false
+	} else if err != nil {
+		fs.report(file, err)
+		return false
+	}
+	info, err := fd.Stat()
+	if err != nil {
+		fd.Close()
+		fs.report(file, err)
+		return false
+	}
+	if file.info != nil && !os.SameFile(file.info, info) {
+		file.restart()
+	}
+	file.fd = fd
+	file.info = info
+	file.active = time.Now()
+	return true
+}
+
+// Read whatever's been added to a file since we last looked
+func (fs *followState) poll(ctx context.Context, file *followedFile) {
+	if file.fd == nil {
+		current, err := os.Stat(filepath.Join(fs.Directory, file.name))
+		if os.IsNotExist(err) {
+			delete(fs.files, file.name)
+			return
+		} else if err == nil && file.info != nil && os.SameFile(file.info, current) && current.Size() == file.offset {
+			// Still idle
+			return
+		}
+		if !fs.open(file) {
+			return
+		}
+	}
+
+	// A file which has been renamed away or deleted may still have lines we haven't read
+	current, err := os.Stat(filepath.Join(fs.Directory, file.name))
+	replaced := err != nil || !os.SameFile(file.info, current)
+
+	info, err := file.fd.Stat()
+	if err != nil {
+		fs.report(file, err)
+		fs.closeFile(file)
+		return
+	}
+	if info.Size() < file.offset {
+		file.restart()
+	}
+	if !fs.readNew(ctx, file) {
+		return
+	}
+
+	if replaced {
+		fs.closeFile(file)
+		file.restart()
+		if fs.open(file) {
+			fs.readNew(ctx, file)
+		}
+	} else if time.Since(file.active) > fs.IdleTimeout {
+		// Closing idle files means Windows will let their owners rename or delete them
+		fs.closeFile(file)
+	}
+}
+
+// Read from the file's offset to its end, sending each complete line.  Returns false if the context is
+// done or the file couldn't be read.
+func (fs *followState) readNew(ctx context.Context, file *followedFile) bool {
+	buffer := make([]byte, 4096)
+	for {
+		cnt, err := file.fd.ReadAt(buffer, file.offset)
+		if cnt > 0 {
+			file.offset += int64(cnt)
+			file.active = time.Now()
+			file.partial = append(file.partial, buffer[:cnt]...)
+			if !fs.sendLines(ctx, file) {
+				return false
+			}
+		}
+		if err == io.EOF {
+			file.lastErr = ""
+			return true
+		} else if err != nil {
+			fs.report(file, err)
+			fs.closeFile(file)
+			return false
+		}
+	}
+}
+
+func (fs *followState) sendLines(ctx context.Context, file *followedFile) bool {
+	for ib := bytes.IndexByte(file.partial, '\n'); ib >= 0; ib = bytes.IndexByte(file.partial, '\n') {
+		text := strings.TrimRight(string(file.partial[:ib]), "\r")
+		file.partial = file.partial[ib+1:]
+		if file.skipping {
+			file.skipping = false
+			continue
+		}
+		select {
+		case fs.lines <- Line{File: file.name, Text: text}:
+		case <-ctx.Done():
+			return false
+		}
+	}
+	file.partial = append([]byte(nil), file.partial...)
+	return true
+}
+
+// Report a problem with a file, once until it's resolved
+func (fs *followState) report(file *followedFile, err error) {
+	if err.Error() != file.lastErr {
+		file.lastErr = err.Error()
+		fs.OnError(fmt.Errorf("following %v: %w", file.name, err))
+	}
+}
+
+func (fs *followState) closeFile(file *followedFile) {
+	if file.fd != nil {
+		file.fd.Close()
+		file.fd = nil
+	}
+}
+
+func (fs *followState) closeAll() {
+	for _, file := range fs.files {
+		fs.closeFile(file)
+	}
+}
+
+// Start reading a file from the beginning, e.g. after it was truncated or replaced
+func (file *followedFile) restart() {
+	file.offset = 0
+	file.partial = nil
+	file.skipping = false
+}
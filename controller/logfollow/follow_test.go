@@ -0,0 +1,155 @@
+package logfollow
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func startFollower(t *testing.T, dir string, notify bool, setup func(f *Follower)) (<-chan Line, func()) {
+	f := New(dir, func(name string) bool { return strings.HasSuffix(name, ".txt") })
+	f.PollInterval = 10 * time.Millisecond
+	f.RescanInterval = 50 * time.Millisecond
+	f.UseNotifications = notify
+	f.OnError = func(err error) { t.Logf("follower: %v", err) }
+	if setup != nil {
+		setup(f)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	lines, err := f.Start(ctx)
+	if err != nil {
+		cancel()
+		t.Fatal(err)
+	}
+	return lines, func() {
+		cancel()
+		for range lines {
+		}
+	}
+}
+
+func appendTo(t *testing.T, path string, text string) {
+	fd, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fd.Close()
+	if _, err = fd.WriteString(text); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func expectLine(t *testing.T, lines <-chan Line, file string, text string) {
+	t.Helper()
+	select {
+	case line := <-lines:
+		if line.File != file || line.Text != text {
+			t.Fatalf("Expected %v: %v, got %v: %v", file, text, line.File, line.Text)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("Timed out waiting for %v: %v", file, text)
+	}
+}
+
+func expectNothing(t *testing.T, lines <-chan Line) {
+	t.Helper()
+	select {
+	case line := <-lines:
+		t.Fatalf("Expected nothing, got %v: %v", line.File, line.Text)
+	case <-time.After(200 * time.Millisecond):
+	}
+}
+
+func testFollow(t *testing.T, notify bool) {
+	dir := t.TempDir()
+	log := filepath.Join(dir, "eqlog_Jephine_xegony.txt")
+	appendTo(t, log, "[old] Already here\r\n[old] Half a li")
+	lines, done := startFollower(t, dir, notify, nil)
+	defer done()
+
+	// Lines from before we started are skipped, including the end of one being written
+	appendTo(t, log, "ne\r\n[new] First\r\n")
+	expectLine(t, lines, "eqlog_Jephine_xegony.txt", "[new] First")
+
+	// Partial lines wait for their ending
+	appendTo(t, log, "[new] Sec")
+	expectNothing(t, lines)
+	appendTo(t, log, "ond\r\n")
+	expectLine(t, lines, "eqlog_Jephine_xegony.txt", "[new] Second")
+
+	// Files created later are read from the beginning; other files are ignored
+	appendTo(t, filepath.Join(dir, "notes.log"), "ignored\n")
+	appendTo(t, filepath.Join(dir, "eqlog_Joramar_xegony.txt"), "[new] Alt\r\n")
+	expectLine(t, lines, "eqlog_Joramar_xegony.txt", "[new] Alt")
+
+	// Truncation starts over
+	if err := os.Truncate(log, 0); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(100 * time.Millisecond)
+	appendTo(t, log, "[new] After truncation\r\n")
+	expectLine(t, lines, "eqlog_Jephine_xegony.txt", "[new] After truncation")
+
+	// So does archiving the log and starting a new one
+	if err := os.Rename(log, filepath.Join(dir, "archive.old")); err != nil {
+		t.Fatal(err)
+	}
+	appendTo(t, log, "[new] Fresh log\r\n")
+	expectLine(t, lines, "eqlog_Jephine_xegony.txt", "[new] Fresh log")
+	expectNothing(t, lines)
+}
+
+func TestFollow_Notifications(t *testing.T) {
+	testFollow(t, true)
+}
+
+func TestFollow_Polling(t *testing.T) {
+	testFollow(t, false)
+}
+
+func TestFollow_Idle(t *testing.T) {
+	dir := t.TempDir()
+	log := filepath.Join(dir, "eqlog_Jephine_xegony.txt")
+	appendTo(t, log, "")
+	lines, done := startFollower(t, dir, false, func(f *Follower) {
+		f.IdleTimeout = 20 * time.Millisecond
+	})
+	defer done()
+
+	appendTo(t, log, "[new] Before idling\n")
+	expectLine(t, lines, "eqlog_Jephine_xegony.txt", "[new] Before idling")
+	time.Sleep(100 * time.Millisecond)
+	appendTo(t, log, "[new] After idling\n")
+	expectLine(t, lines, "eqlog_Jephine_xegony.txt", "[new] After idling")
+}
+
+func TestFollow_RemovedFile(t *testing.T) {
+	dir := t.TempDir()
+	lines, done := startFollower(t, dir, false, nil)
+	defer done()
+
+	log := filepath.Join(dir, "eqlog_Jephine_xegony.txt")
+	appendTo(t, log, "[new] Short lived\n")
+	expectLine(t, lines, "eqlog_Jephine_xegony.txt", "[new] Short lived")
+	if err := os.Remove(log); err != nil {
+		t.Fatal(err)
+	}
+	expectNothing(t, lines)
+	appendTo(t, log, "[new] Back again\n")
+	expectLine(t, lines, "eqlog_Jephine_xegony.txt", "[new] Back again")
+}
+
+func TestFollow_MissingDirectory(t *testing.T) {
+	dir, err := ioutil.TempDir("", "logfollow")
+	if err != nil {
+		t.Fatal(err)
+	}
+	os.RemoveAll(dir)
+	if _, err := New(dir, func(string) bool { return true }).Start(context.Background()); err == nil {
+		t.Fatal("Expected an error for a missing directory")
+	}
+}
@@ -1,6 +1,9 @@
 package plugin
 
-import lua "github.com/yuin/gopher-lua"
+import (
+	"github.com/gontikr99/bidbot2/controller/storage"
+	lua "github.com/yuin/gopher-lua"
+)
 
 func getMembers(state *lua.LState) int {
 	gp := guildPlugin(state)
@@ -26,8 +29,73 @@ func getMembers(state *lua.LState) int {
 	return 1
 }
 
+func getRaidMembers(state *lua.LState) int {
+	gp := guildPlugin(state)
+	if gp.raidMemberReader == nil {
+		panic("EverQuest is not yet present")
+	}
+	members, err := gp.raidMemberReader.RaidMembers()
+	if err != nil {
+		panic(err)
+	}
+	memberMap := state.NewTable()
+	for name, member := range members {
+		memberTable := state.NewTable()
+		state.SetField(memberTable, "group", lua.LNumber(member.Group))
+		state.SetField(memberTable, "level", lua.LNumber(member.Level))
+		state.SetField(memberTable, "class", lua.LString(member.Class))
+		state.SetField(memberTable, "role", lua.LString(member.Role))
+		state.SetField(memberMap, name, memberTable)
+	}
+	state.Push(memberMap)
+	return 1
+}
+
+// Raid night attendance percentage of a character.  With a number of days, returns the percentage over
+// those days (or nil if there were no raids); otherwise returns a table of percentages keyed by 30, 60 and 90.
+func getAttendance(state *lua.LState) int {
+	charname := state.CheckString(1)
+	percentage := func(days int) lua.LValue {
+		attended, nights, err := storage.Attendance(charname, days)
+		if err != nil {
+			panic(err)
+		}
+		if nights == 0 {
+			return lua.LNil
+		}
+		return lua.LNumber(float64(attended) * 100 / float64(nights))
+	}
+	if state.GetTop() >= 2 {
+		state.Push(percentage(state.CheckInt(2)))
+		return 1
+	}
+	result := state.NewTable()
+	for _, days := range []int{30, 60, 90} {
+		result.RawSetInt(days, percentage(days))
+	}
+	state.Push(result)
+	return 1
+}
+
+// Table of the alt to main overrides recorded with !setmain, keyed by alt
+func getMainOverrides(state *lua.LState) int {
+	overrides, err := storage.MainOverrides()
+	if err != nil {
+		panic(err)
+	}
+	result := state.NewTable()
+	for alt, main := range overrides {
+		result.RawSetString(alt, lua.LString(main))
+	}
+	state.Push(result)
+	return 1
+}
+
 var eqExports = map[string]lua.LGFunction{
-	"guildmembers": getMembers,
+	"guildmembers":  getMembers,
+	"attendance":    getAttendance,
+	"raidmembers":   getRaidMembers,
+	"mainoverrides": getMainOverrides,
 }
 
 func eqLoader(state *lua.LState) int {
@@ -0,0 +1,86 @@
+package plugin
+
+import (
+	"github.com/gontikr99/bidbot2/controller/storage"
+	lua "github.com/yuin/gopher-lua"
+	"time"
+)
+
+// Convert auction records into a Lua list of tables
+func pushAuctionRecords(state *lua.LState, records []storage.AuctionRecord) {
+	list := state.NewTable()
+	for _, record := range records {
+		recTable := state.NewTable()
+		state.SetField(recTable, "id", lua.LNumber(record.ID))
+		state.SetField(recTable, "item", lua.LString(record.ItemName))
+		state.SetField(recTable, "count", lua.LNumber(record.Count))
+		state.SetField(recTable, "startedby", lua.LString(record.StartedBy))
+		state.SetField(recTable, "start", lua.LNumber(record.Start.Unix()))
+		state.SetField(recTable, "finish", lua.LNumber(record.End.Unix()))
+		state.SetField(recTable, "price", lua.LNumber(record.Price))
+		winners := state.NewTable()
+		for _, winner := range record.Winners {
+			winners.Append(lua.LString(winner))
+		}
+		state.SetField(recTable, "winners", winners)
+		state.SetField(recTable, "final", lua.LBool(record.Final))
+		state.SetField(recTable, "rolled", lua.LBool(record.Rolled))
+		state.SetField(recTable, "zone", lua.LString(record.Zone))
+		state.SetField(recTable, "boss", lua.LString(record.Boss))
+		awards := state.NewTable()
+		for _, award := range record.CurrentAwards() {
+			awardTable := state.NewTable()
+			state.SetField(awardTable, "character", lua.LString(award.Character))
+			state.SetField(awardTable, "price", lua.LNumber(award.Price))
+			awards.Append(awardTable)
+		}
+		state.SetField(recTable, "awards", awards)
+		bids := state.NewTable()
+		for bidder, bid := range record.Bids {
+			state.SetField(bids, bidder, lua.LNumber(bid))
+		}
+		state.SetField(recTable, "bids", bids)
+		list.Append(recTable)
+	}
+	state.Push(list)
+}
+
+func historyOfItem(state *lua.LState) int {
+	records, err := storage.AuctionsOfItem(state.CheckString(1))
+	if err != nil {
+		panic(err)
+	}
+	pushAuctionRecords(state, records)
+	return 1
+}
+
+func historyWonBy(state *lua.LState) int {
+	records, err := storage.AuctionsWonBy(state.CheckString(1))
+	if err != nil {
+		panic(err)
+	}
+	pushAuctionRecords(state, records)
+	return 1
+}
+
+func historySince(state *lua.LState) int {
+	seconds := state.CheckNumber(1)
+	records, err := storage.AuctionHistory(time.Now().Add(-time.Duration(float64(seconds) * float64(time.Second))))
+	if err != nil {
+		panic(err)
+	}
+	pushAuctionRecords(state, records)
+	return 1
+}
+
+var historyExports = map[string]lua.LGFunction{
+	"item":  historyOfItem,
+	"won":   historyWonBy,
+	"since": historySince,
+}
+
+func historyLoader(state *lua.LState) int {
+	mod := state.SetFuncs(state.NewTable(), historyExports)
+	state.Push(mod)
+	return 1
+}
@@ -0,0 +1,51 @@
+package plugin
+
+import (
+	"github.com/gontikr99/bidbot2/controller/items"
+	lua "github.com/yuin/gopher-lua"
+)
+
+func stringList(state *lua.LState, values []string) *lua.LTable {
+	list := state.NewTable()
+	for _, value := range values {
+		list.Append(lua.LString(value))
+	}
+	return list
+}
+
+// Look up an item by (approximate) name, returning a table describing it, or nil if it isn't known
+func itemsFind(state *lua.LState) int {
+	item := items.Find(state.CheckString(1))
+	if item == nil {
+		state.Push(lua.LNil)
+		return 1
+	}
+	itemTable := state.NewTable()
+	state.SetField(itemTable, "id", lua.LNumber(item.ID))
+	state.SetField(itemTable, "name", lua.LString(item.Name))
+	state.SetField(itemTable, "slots", stringList(state, item.Slots))
+	state.SetField(itemTable, "classes", stringList(state, item.Classes))
+	state.SetField(itemTable, "races", stringList(state, item.Races))
+	state.SetField(itemTable, "lore", lua.LBool(item.Lore))
+	state.SetField(itemTable, "nodrop", lua.LBool(item.NoDrop))
+	state.Push(itemTable)
+	return 1
+}
+
+// Check whether a class can use the named item.  Unknown items are usable by everyone.
+func itemsUsableBy(state *lua.LState) int {
+	item := items.Find(state.CheckString(1))
+	state.Push(lua.LBool(item == nil || item.UsableBy(state.CheckString(2))))
+	return 1
+}
+
+var itemsExports = map[string]lua.LGFunction{
+	"find":     itemsFind,
+	"usableby": itemsUsableBy,
+}
+
+func itemsLoader(state *lua.LState) int {
+	mod := state.SetFuncs(state.NewTable(), itemsExports)
+	state.Push(mod)
+	return 1
+}
@@ -0,0 +1,71 @@
+package plugin
+
+import (
+	"github.com/gontikr99/bidbot2/controller/storage"
+	lua "github.com/yuin/gopher-lua"
+)
+
+// ledger.balance(main): the main's DKP according to the ledger, or nil if the ledger has nothing for them
+func ledgerBalance(state *lua.LState) int {
+	charname := state.CheckString(1)
+	known, err := storage.InLedger(charname)
+	if err != nil {
+		panic(err)
+	}
+	if !known {
+		state.Push(lua.LNil)
+		return 1
+	}
+	balance, err := storage.LedgerBalance(charname)
+	if err != nil {
+		panic(err)
+	}
+	state.Push(lua.LNumber(balance))
+	return 1
+}
+
+// ledger.balances(): a table mapping every main in the ledger to their DKP
+func ledgerBalances(state *lua.LState) int {
+	balances, err := storage.LedgerBalances()
+	if err != nil {
+		panic(err)
+	}
+	result := state.NewTable()
+	for charname, balance := range balances {
+		state.SetField(result, charname, lua.LNumber(balance))
+	}
+	state.Push(result)
+	return 1
+}
+
+// ledger.entries(main): the main's ledger entries, oldest first
+func ledgerEntries(state *lua.LState) int {
+	entries, err := storage.LedgerEntriesOf(state.CheckString(1))
+	if err != nil {
+		panic(err)
+	}
+	list := state.NewTable()
+	for _, entry := range entries {
+		entryTable := state.NewTable()
+		state.SetField(entryTable, "when", lua.LNumber(entry.When.Unix()))
+		state.SetField(entryTable, "amount", lua.LNumber(entry.Amount))
+		state.SetField(entryTable, "kind", lua.LString(entry.Kind))
+		state.SetField(entryTable, "reason", lua.LString(entry.Reason))
+		state.SetField(entryTable, "by", lua.LString(entry.By))
+		list.Append(entryTable)
+	}
+	state.Push(list)
+	return 1
+}
+
+var ledgerExports = map[string]lua.LGFunction{
+	"balance":  ledgerBalance,
+	"balances": ledgerBalances,
+	"entries":  ledgerEntries,
+}
+
+func ledgerLoader(state *lua.LState) int {
+	mod := state.SetFuncs(state.NewTable(), ledgerExports)
+	state.Push(mod)
+	return 1
+}
@@ -12,6 +12,7 @@ import (
 	"log"
 	"math"
 	"strings"
+	"time"
 )
 
 type GuildPlugin struct {
@@ -19,6 +20,7 @@ type GuildPlugin struct {
 	context           context.Context
 	web               storage.WebCache
 	guildRecordReader everquest.GuildRecordsReader
+	raidMemberReader  everquest.RaidMembersReader
 	discord           *discord.Client
 
 	dkpFunc         lua.LValue
@@ -26,6 +28,9 @@ type GuildPlugin struct {
 	validateBidFunc lua.LValue
 	sortBidsFunc    lua.LValue
 	solicitFunc     lua.LValue
+	scheduleFunc    lua.LValue
+	parseBidFunc    lua.LValue
+	shouldAucFunc   lua.LValue
 	actions         chan<- luaRequest
 }
 
@@ -58,6 +63,9 @@ func newGuildPlugin(ctx context.Context, web storage.WebCache, sourceRunner func
 	result.state.PreloadModule("re", gluare.Loader)
 	result.state.PreloadModule("http", httpLoader)
 	result.state.PreloadModule("everquest", eqLoader)
+	result.state.PreloadModule("history", historyLoader)
+	result.state.PreloadModule("items", itemsLoader)
+	result.state.PreloadModule("ledger", ledgerLoader)
 	result.state.SetGlobal("print", result.state.NewFunction(logPrint))
 
 	err = sourceRunner(result.state)
@@ -98,6 +106,11 @@ func newGuildPlugin(ctx context.Context, web storage.WebCache, sourceRunner func
 		return
 	}
 
+	// Optional hooks
+	result.scheduleFunc = result.state.GetGlobal("auctionschedule")
+	result.parseBidFunc = result.state.GetGlobal("parsebid")
+	result.shouldAucFunc = result.state.GetGlobal("shouldauction")
+
 	result.state.SetContext(ctx)
 	actChan := make(chan luaRequest)
 	result.actions = actChan
@@ -124,6 +137,7 @@ func (gp *GuildPlugin) submit(callback func() (lua.LValue, error)) (lua.LValue,
 func (gp *GuildPlugin) SetEqClient(everquest *everquest.Client) {
 	_, _ = gp.submit(func() (lua.LValue, error) {
 		gp.guildRecordReader = everquest
+		gp.raidMemberReader = everquest
 		return nil, nil
 	})
 }
@@ -135,7 +149,14 @@ func (gp *GuildPlugin) SetDiscordClient(discord *discord.Client) {
 	})
 }
 
+// Overrides recorded with !setmain win over whatever the getmain hook would work out from the guild notes
 func (gp *GuildPlugin) GetMain(charname string) (string, error) {
+	override, err := storage.MainOverrideOf(charname)
+	if err != nil {
+		return "", err
+	} else if override != nil {
+		return override.Main, nil
+	}
 	value, err := gp.submit(func() (lua.LValue, error) {
 		err := gp.state.CallByParam(lua.P{
 			Fn:      gp.mainFunc,
@@ -189,13 +210,40 @@ func (gp *GuildPlugin) GetDKP(charname string) (float64, error) {
 	}
 }
 
-func (gp *GuildPlugin) ValidateBid(charname string, bid float64) (string, error) {
+// What's being auctioned, handed to the rules script so that it can apply rules for particular items
+type AuctionContext struct {
+	ItemName  string
+	Count     int
+	Zone      string // Empty when the zone isn't known
+	Boss      string // Boss killed shortly before the auction, or empty
+	StartedBy string
+	Start     time.Time
+}
+
+// Convert an auction context into a Lua table, or nil if there isn't one
+func (ac *AuctionContext) toLua(state *lua.LState) lua.LValue {
+	if ac == nil {
+		return lua.LNil
+	}
+	table := state.NewTable()
+	state.SetField(table, "item", lua.LString(ac.ItemName))
+	state.SetField(table, "count", lua.LNumber(ac.Count))
+	state.SetField(table, "zone", lua.LString(ac.Zone))
+	state.SetField(table, "boss", lua.LString(ac.Boss))
+	state.SetField(table, "startedby", lua.LString(strings.ToLower(ac.StartedBy)))
+	state.SetField(table, "start", lua.LNumber(ac.Start.Unix()))
+	return table
+}
+
+// Check whether a bid is allowed.  The auction context is passed to the script as an extra argument, which
+// scripts written before it existed simply ignore.
+func (gp *GuildPlugin) ValidateBid(charname string, bid float64, ac *AuctionContext) (string, error) {
 	value, err := gp.submit(func() (lua.LValue, error) {
 		err := gp.state.CallByParam(lua.P{
 			Fn:      gp.validateBidFunc,
 			NRet:    1,
 			Protect: true,
-		}, lua.LString(strings.ToLower(charname)), lua.LNumber(bid))
+		}, lua.LString(strings.ToLower(charname)), lua.LNumber(bid), ac.toLua(gp.state))
 		if err != nil {
 			return nil, err
 		}
@@ -239,12 +287,194 @@ func (gp *GuildPlugin) Solicit(itemName string) (string, error) {
 	}
 }
 
+// Ask the plugin how long an auction of the specified item should last.  The plugin's `auctionschedule` function
+// is optional; it may return nil to accept the default schedule, or a table overriding any of `duration`,
+// `warnings` (a list), `snipewindow` or `snipeextend`, all in seconds.
+func (gp *GuildPlugin) AuctionSchedule(itemName string, count int, defaultSchedule *storage.AuctionSchedule) (*storage.AuctionSchedule, error) {
+	if gp.scheduleFunc == lua.LNil {
+		return defaultSchedule, nil
+	}
+	result := *defaultSchedule
+	_, err := gp.submit(func() (lua.LValue, error) {
+		err := gp.state.CallByParam(lua.P{
+			Fn:      gp.scheduleFunc,
+			NRet:    1,
+			Protect: true,
+		}, lua.LString(itemName), lua.LNumber(count))
+		if err != nil {
+			return nil, err
+		}
+		ret := gp.state.Get(-1)
+		gp.state.Pop(1)
+		if ret.Type() == lua.LTNil {
+			return nil, nil
+		}
+		table, ok := ret.(*lua.LTable)
+		if !ok {
+			return nil, fmt.Errorf("auctionschedule function didn't return a table or nil, but a %v", ret.Type())
+		}
+		seconds := func(value lua.LValue) time.Duration {
+			return time.Duration(float64(lua.LVAsNumber(value)) * float64(time.Second))
+		}
+		if value := table.RawGetString("duration"); value.Type() == lua.LTNumber {
+			result.Duration = seconds(value)
+		}
+		if value := table.RawGetString("warnings"); value.Type() == lua.LTTable {
+			result.Warnings = make([]time.Duration, 0)
+			value.(*lua.LTable).ForEach(func(_ lua.LValue, warning lua.LValue) {
+				if warning.Type() == lua.LTNumber {
+					result.Warnings = append(result.Warnings, seconds(warning))
+				}
+			})
+		}
+		if value := table.RawGetString("snipewindow"); value.Type() == lua.LTNumber {
+			result.SnipeWindow = seconds(value)
+		}
+		if value := table.RawGetString("snipeextend"); value.Type() == lua.LTNumber {
+			result.SnipeExtend = seconds(value)
+		}
+		if value := table.RawGetString("openidle"); value.Type() == lua.LTNumber {
+			result.OpenIdle = seconds(value)
+		}
+		if value := table.RawGetString("openincrement"); value.Type() == lua.LTNumber {
+			result.OpenIncrement = float64(lua.LVAsNumber(value))
+		}
+		return nil, nil
+	})
+	if err != nil {
+		return defaultSchedule, err
+	}
+	if result.Duration <= 0 {
+		return defaultSchedule, errors.New("auctionschedule function returned a duration which isn't positive")
+	}
+	if result.OpenIdle <= 0 {
+		return defaultSchedule, errors.New("auctionschedule function returned an openidle which isn't positive")
+	}
+	return &result, nil
+}
+
 type BidDesc struct {
 	BidderDesc string
 	BidDesc    string
 }
 
-func (gp *GuildPlugin) SortBids(rawBids map[string]float64, count int) (price float64, winners []string, displayBids []BidDesc, err error) {
+// What a bidder meant by a tell
+type BidAction int
+
+const (
+	BidAmount BidAction = iota // Bid `Amount`
+	BidAllIn                   // Bid everything they've got
+	BidCancel                  // Withdraw their bid
+	BidReject                  // Not a bid; `Reason` says why, or is empty for the standard explanation
+)
+
+type ParsedBid struct {
+	Action BidAction
+	Amount float64
+	Reason string
+}
+
+// Ask the optional parsebid hook what a tell means.  Returns nil if there's no hook, or if the hook leaves
+// the tell to BidBot2's own parsing.  The hook returns one of:
+// - a number: the bid (0 cancels)
+// - "cancel", or "all"/"max" to bid everything the bidder has
+// - nil and a message: the tell isn't an acceptable bid
+// - nil: BidBot2 should parse the tell itself
+func (gp *GuildPlugin) ParseBid(charname string, text string, ac *AuctionContext) (parsed *ParsedBid, err error) {
+	if gp.parseBidFunc == lua.LNil {
+		return nil, nil
+	}
+	_, err = gp.submit(func() (lua.LValue, error) {
+		err := gp.state.CallByParam(lua.P{
+			Fn:      gp.parseBidFunc,
+			NRet:    2,
+			Protect: true,
+		}, lua.LString(strings.ToLower(charname)), lua.LString(text), ac.toLua(gp.state))
+		if err != nil {
+			return nil, err
+		}
+		ret, reason := gp.state.Get(-2), gp.state.Get(-1)
+		gp.state.Pop(2)
+		switch ret.Type() {
+		case lua.LTNumber:
+			amount := float64(lua.LVAsNumber(ret))
+			if amount == 0 {
+				parsed = &ParsedBid{Action: BidCancel}
+			} else {
+				parsed = &ParsedBid{Action: BidAmount, Amount: amount}
+			}
+		case lua.LTString:
+			switch strings.ToLower(lua.LVAsString(ret)) {
+			case "cancel":
+				parsed = &ParsedBid{Action: BidCancel}
+			case "all", "max":
+				parsed = &ParsedBid{Action: BidAllIn}
+			default:
+				return nil, fmt.Errorf("parsebid function returned an unknown keyword '%v'", lua.LVAsString(ret))
+			}
+		case lua.LTNil:
+			if reason.Type() == lua.LTString {
+				parsed = &ParsedBid{Action: BidReject, Reason: lua.LVAsString(reason)}
+			}
+		default:
+			return nil, fmt.Errorf("parsebid function didn't return a number, string or nil, but a %v", ret.Type())
+		}
+		return nil, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return parsed, nil
+}
+
+// What to do with an item which was just looted
+type LootAction int
+
+const (
+	LootOffer   LootAction = iota // Post it in Discord, for an officer to decide whether to auction it
+	LootAuction                   // Queue an auction of it straight away
+	LootIgnore                    // Leave it be
+)
+
+// Ask the optional shouldauction hook what to do with a looted item.  The hook receives the item name and the
+// looter, and returns true to auction the item, false to ignore it, or nil to offer it to the officers, which
+// is also what happens without the hook.
+func (gp *GuildPlugin) ShouldAuction(itemName string, looter string) (LootAction, error) {
+	if gp.shouldAucFunc == lua.LNil {
+		return LootOffer, nil
+	}
+	value, err := gp.submit(func() (lua.LValue, error) {
+		err := gp.state.CallByParam(lua.P{
+			Fn:      gp.shouldAucFunc,
+			NRet:    1,
+			Protect: true,
+		}, lua.LString(itemName), lua.LString(strings.ToLower(looter)))
+		if err != nil {
+			return nil, err
+		}
+		ret := gp.state.Get(-1)
+		gp.state.Pop(1)
+		return ret, nil
+	})
+	if err != nil {
+		return LootOffer, err
+	}
+	switch value.Type() {
+	case lua.LTNil:
+		return LootOffer, nil
+	case lua.LTBool:
+		if lua.LVAsBool(value) {
+			return LootAuction, nil
+		}
+		return LootIgnore, nil
+	default:
+		return LootOffer, fmt.Errorf("shouldauction function didn't return a boolean or nil, but a %v", value.Type())
+	}
+}
+
+// Determine the winners of an auction, and how to display the outcome.  As with ValidateBid, the auction
+// context is an extra argument.
+func (gp *GuildPlugin) SortBids(rawBids map[string]float64, count int, ac *AuctionContext) (price float64, winners []string, displayBids []BidDesc, err error) {
 	_, _ = gp.submit(func() (lua.LValue, error) {
 		rawBidsTable := gp.state.NewTable()
 		for bidder, bid := range rawBids {
@@ -254,7 +484,7 @@ func (gp *GuildPlugin) SortBids(rawBids map[string]float64, count int) (price fl
 			Fn:      gp.sortBidsFunc,
 			NRet:    3,
 			Protect: true,
-		}, rawBidsTable, lua.LNumber(count))
+		}, rawBidsTable, lua.LNumber(count), ac.toLua(gp.state))
 		if err != nil {
 			return nil, err
 		}
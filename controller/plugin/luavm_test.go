@@ -3,6 +3,8 @@ package plugin
 import (
 	"context"
 	"github.com/gontikr99/bidbot2/controller/everquest"
+	"github.com/gontikr99/bidbot2/controller/storage"
+	lua "github.com/yuin/gopher-lua"
 	"log"
 	"testing"
 	"time"
@@ -17,6 +19,14 @@ func (cr *constRecord) GuildRecords() (gr map[string]*everquest.GuildRecord, err
 	return
 }
 
+type constRaid struct {
+	members map[string]*storage.RaidMemberRecord
+}
+
+func (cr *constRaid) RaidMembers() (map[string]*storage.RaidMemberRecord, error) {
+	return cr.members, nil
+}
+
 type dummyWebCache struct {
 }
 
@@ -142,7 +152,7 @@ func TestGuildPlugin_SortBids(t *testing.T) {
 		"Jephine": 101,
 		"Joramar": 101,
 	}
-	price, winners, _, err := vm.SortBids(bids, 1)
+	price, winners, _, err := vm.SortBids(bids, 1, nil)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -157,7 +167,7 @@ func TestGuildPlugin_SortBids(t *testing.T) {
 		"Dalamin": 200,
 		"Jephine": 150,
 	}
-	price, winners, _, err = vm.SortBids(bids, 1)
+	price, winners, _, err = vm.SortBids(bids, 1, nil)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -173,7 +183,7 @@ func TestGuildPlugin_SortBids(t *testing.T) {
 		"Jephine": 200,
 		"Piddles": 100,
 	}
-	price, winners, _, err = vm.SortBids(bids, 1)
+	price, winners, _, err = vm.SortBids(bids, 1, nil)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -189,7 +199,7 @@ func TestGuildPlugin_SortBids(t *testing.T) {
 		"Joramar": 110,
 		"Piddles": 75,
 	}
-	price, winners, _, err = vm.SortBids(bids, 1)
+	price, winners, _, err = vm.SortBids(bids, 1, nil)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -203,7 +213,7 @@ func TestGuildPlugin_SortBids(t *testing.T) {
 	bids = map[string]float64{
 		"Geoffrey": 200,
 	}
-	price, winners, _, err = vm.SortBids(bids, 1)
+	price, winners, _, err = vm.SortBids(bids, 1, nil)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -220,7 +230,7 @@ func TestGuildPlugin_SortBids(t *testing.T) {
 		"Piddles": 75,
 	}
 	var display []BidDesc
-	price, winners, display, err = vm.SortBids(bids, 2)
+	price, winners, display, err = vm.SortBids(bids, 2, nil)
 	log.Println(price, winners, display)
 	if err != nil {
 		t.Fatal(err)
@@ -232,3 +242,188 @@ func TestGuildPlugin_SortBids(t *testing.T) {
 		t.Fatalf("Expected Joramar+Larryy to win")
 	}
 }
+
+func TestGuildPlugin_AuctionSchedule(t *testing.T) {
+	ctx, done := context.WithCancel(context.Background())
+	defer done()
+	vm, err := newGuildPlugin(ctx, &dummyWebCache{}, func(state *lua.LState) error {
+		return state.DoString(`
+			function auctionschedule(item, count)
+				if item == "Cloak of Flames" then
+					return {duration=120, warnings={60, 20}, snipewindow=10, snipeextend=15}
+				end
+				return nil
+			end`)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defaultSchedule := storage.DefaultAuctionSchedule()
+	schedule, err := vm.AuctionSchedule("Rusty Dagger", 1, defaultSchedule)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if schedule.Duration != defaultSchedule.Duration {
+		t.Fatalf("Expected default schedule, got %v", schedule)
+	}
+
+	schedule, err = vm.AuctionSchedule("Cloak of Flames", 1, defaultSchedule)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if schedule.Duration != 120*time.Second || schedule.SnipeWindow != 10*time.Second || schedule.SnipeExtend != 15*time.Second {
+		t.Fatalf("Expected 120 second auction with anti-snipe, got %v", schedule)
+	}
+	warnings := schedule.SortedWarnings()
+	if len(warnings) != 2 || warnings[0] != 60*time.Second || warnings[1] != 20*time.Second {
+		t.Fatalf("Expected warnings at 60 and 20 seconds, got %v", warnings)
+	}
+}
+
+func TestGuildPlugin_AuctionContext(t *testing.T) {
+	ctx, done := context.WithCancel(context.Background())
+	defer done()
+	vm, err := newGuildPlugin(ctx, &dummyWebCache{}, func(state *lua.LState) error {
+		return state.DoString(`
+			function validatebid(charname, quantity, auction)
+				if auction ~= nil and auction.item == "Cloak of Flames" and quantity < 500 then
+					return "Bids on "..auction.item.." from "..auction.startedby.." start at 500."
+				end
+				return nil
+			end
+			function sortbids(bids, count)
+				return 0, {}, {}
+			end`)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ac := &AuctionContext{ItemName: "Cloak of Flames", Count: 1, StartedBy: "Alice", Start: time.Now()}
+	if msg, err := vm.ValidateBid("bob", 100, ac); err != nil || msg != "Bids on Cloak of Flames from alice start at 500." {
+		t.Fatalf("Expected bid to be rejected, got %v %v", msg, err)
+	}
+	ac.ItemName = "Rusty Dagger"
+	if msg, err := vm.ValidateBid("bob", 100, ac); err != nil || msg != "" {
+		t.Fatalf("Expected bid to be accepted, got %v %v", msg, err)
+	}
+	if msg, err := vm.ValidateBid("bob", 100, nil); err != nil || msg != "" {
+		t.Fatalf("Expected bid without context to be accepted, got %v %v", msg, err)
+	}
+	// A sortbids taking only two arguments ignores the context
+	if _, winners, _, err := vm.SortBids(map[string]float64{"bob": 100}, 1, ac); err != nil || len(winners) != 0 {
+		t.Fatalf("Expected no winners, got %v %v", winners, err)
+	}
+}
+
+func TestGuildPlugin_ParseBid(t *testing.T) {
+	ctx, done := context.WithCancel(context.Background())
+	defer done()
+	vm, err := newGuildPlugin(ctx, &dummyWebCache{}, func(state *lua.LState) error {
+		return state.DoString(`
+			function validatebid(charname, quantity)
+				return nil
+			end
+			function sortbids(bids, count)
+				return 0, {}, {}
+			end
+			function parsebid(charname, text, auction)
+				local amount = string.match(text, "^(%d+) for my alt$")
+				if amount ~= nil then
+					return nil, "Alts can't bid on "..auction.item.."."
+				elseif text == "everything" then
+					return "all"
+				elseif text == "nevermind" then
+					return "cancel"
+				elseif text == "half" then
+					return 50
+				end
+				return nil
+			end`)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ac := &AuctionContext{ItemName: "Cloak of Flames", Count: 1}
+	expect := func(text string, action BidAction, amount float64, reason string) {
+		parsed, err := vm.ParseBid("bob", text, ac)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if parsed == nil || parsed.Action != action || parsed.Amount != amount || parsed.Reason != reason {
+			t.Fatalf("Expected '%v' to parse as %v %v '%v', got %v", text, action, amount, reason, parsed)
+		}
+	}
+	expect("50 for my alt", BidReject, 0, "Alts can't bid on Cloak of Flames.")
+	expect("everything", BidAllIn, 0, "")
+	expect("nevermind", BidCancel, 0, "")
+	expect("half", BidAmount, 50, "")
+	if parsed, err := vm.ParseBid("bob", "150", ac); err != nil || parsed != nil {
+		t.Fatalf("Expected the hook to leave '150' alone, got %v %v", parsed, err)
+	}
+}
+
+func TestGuildPlugin_RaidMembers(t *testing.T) {
+	ctx, done := context.WithCancel(context.Background())
+	defer done()
+	vm, err := newGuildPlugin(ctx, &dummyWebCache{}, func(state *lua.LState) error {
+		return state.DoString(`
+			local eq = require "eq"
+			function validatebid(charname, quantity)
+				local members = eq.raidmembers()
+				if members[charname] == nil then
+					return "You need to be in the raid to bid."
+				end
+				return nil
+			end
+			function sortbids(bids, count)
+				return 0, {}, {}
+			end`)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	vm.raidMemberReader = &constRaid{members: map[string]*storage.RaidMemberRecord{
+		"jephine": {Group: 1, Name: "jephine", Level: 65, Class: "cleric", Role: "Raid Leader"},
+	}}
+	if msg, err := vm.ValidateBid("Jephine", 100, nil); err != nil || msg != "" {
+		t.Fatalf("Expected raid member's bid to be accepted, got %v %v", msg, err)
+	}
+	if msg, err := vm.ValidateBid("Joramar", 100, nil); err != nil || msg != "You need to be in the raid to bid." {
+		t.Fatalf("Expected outsider's bid to be rejected, got %v %v", msg, err)
+	}
+}
+
+func TestGuildPlugin_ShouldAuction(t *testing.T) {
+	ctx, done := context.WithCancel(context.Background())
+	defer done()
+	vm, err := newGuildPlugin(ctx, &dummyWebCache{}, func(state *lua.LState) error {
+		return state.DoString(`
+			function shouldauction(item, looter)
+				if item == "Rusty Dagger" then
+					return false
+				elseif item:sub(1, 6) == "Spell:" then
+					return nil
+				end
+				return true
+			end`)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		item   string
+		action LootAction
+	}{
+		{"Cloak of Flames", LootAuction},
+		{"Rusty Dagger", LootIgnore},
+		{"Spell: Ice Comet", LootOffer},
+	}
+	for _, test := range tests {
+		action, err := vm.ShouldAuction(test.item, "Alice")
+		if err != nil || action != test.action {
+			t.Errorf("%v: expected %v, got %v %v", test.item, test.action, action, err)
+		}
+	}
+}
@@ -0,0 +1,82 @@
+package storage
+
+import (
+	"github.com/timshannon/bolthold"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Raids which run past midnight still count as the night they started on
+const raidNightCutoff = 6 * time.Hour
+
+// One character in a raid dump
+type RaidMemberRecord struct {
+	Group int // 0 when not in a group
+	Name  string
+	Level int
+	Class string
+	Role  string // e.g. "Raid Leader", "Group Leader", or empty
+}
+
+// The raid's members at one point in time
+type RaidSnapshot struct {
+	ID      uint64 `boltholdKey:"ID"`
+	Taken   time.Time
+	Night   string `boltholdIndex:"Night"`
+	Members []RaidMemberRecord
+}
+
+// The raid night a time belongs to, e.g. "2020-11-03"
+func RaidNight(t time.Time) string {
+	return t.Add(-raidNightCutoff).Format("2006-01-02")
+}
+
+// Store a raid dump, filling in its ID and raid night
+func SaveRaidSnapshot(snapshot *RaidSnapshot) error {
+	snapshot.Night = RaidNight(snapshot.Taken)
+	return database.Insert(bolthold.NextSequence(), snapshot)
+}
+
+// All raid dumps taken at or after `since`, oldest first
+func RaidSnapshotsSince(since time.Time) ([]RaidSnapshot, error) {
+	var snapshots []RaidSnapshot
+	err := database.Find(&snapshots, &bolthold.Query{})
+	if err != nil {
+		return nil, err
+	}
+	result := make([]RaidSnapshot, 0, len(snapshots))
+	for _, snapshot := range snapshots {
+		if !snapshot.Taken.Before(since) {
+			result = append(result, snapshot)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Taken.Before(result[j].Taken) })
+	return result, nil
+}
+
+// How many of the raid nights in `snapshots` the named character showed up for
+func countAttendance(snapshots []RaidSnapshot, charname string) (attended int, nights int) {
+	charname = strings.ToLower(charname)
+	allNights := make(map[string]bool)
+	present := make(map[string]bool)
+	for _, snapshot := range snapshots {
+		allNights[snapshot.Night] = true
+		for _, member := range snapshot.Members {
+			if strings.ToLower(member.Name) == charname {
+				present[snapshot.Night] = true
+			}
+		}
+	}
+	return len(present), len(allNights)
+}
+
+// How many raid nights the named character attended over the last `days` days, out of how many there were
+func Attendance(charname string, days int) (attended int, nights int, err error) {
+	snapshots, err := RaidSnapshotsSince(time.Now().AddDate(0, 0, -days))
+	if err != nil {
+		return 0, 0, err
+	}
+	attended, nights = countAttendance(snapshots, charname)
+	return
+}
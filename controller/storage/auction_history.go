@@ -0,0 +1,129 @@
+package storage
+
+import (
+	"github.com/timshannon/bolthold"
+	"sort"
+	"strings"
+	"time"
+)
+
+// A tell received during an auction, exactly as it was sent
+type BidText struct {
+	Bidder   string
+	Text     string
+	Received time.Time
+}
+
+// One row of the bid display computed by the rules plugin
+type BidDisplay struct {
+	BidderDesc string
+	BidDesc    string
+}
+
+// The outcome of auctioning one item.  When several items are auctioned together, each gets its own record,
+// with tells which couldn't be matched to a particular item recorded against all of them.
+type AuctionRecord struct {
+	ID        uint64 `boltholdKey:"ID"`
+	ItemName  string
+	ItemKey   string `boltholdIndex:"ItemKey"`
+	Count     int
+	StartedBy string
+	Start     time.Time
+	End       time.Time
+	BidTexts  []BidText
+	Bids      map[string]float64
+	Winners   []string
+	Price     float64
+	Displays  []BidDisplay
+	Rolled    bool   // Decided by a /random roll-off rather than by bidding
+	Zone      string // Where the auction was held, if known
+	Boss      string // The boss killed shortly before the auction, if any
+
+	// Filled in once an officer confirms or changes the outcome
+	Final  bool
+	Awards []Award
+	Audit  []AuditEntry
+
+	// Where the "Bid end" result was posted on Discord, so reactions to it can be matched up
+	ChannelID string
+	MessageID string `boltholdIndex:"MessageID"`
+}
+
+// Store the record of a finished auction, filling in its ID.  A new record of an auction nobody won is saved as
+// final, as there's no result for an officer to confirm.
+func SaveAuctionRecord(record *AuctionRecord) error {
+	record.ItemKey = strings.ToLower(record.ItemName)
+	if record.ID == 0 && len(record.Winners) == 0 {
+		record.Final = true
+	}
+	if record.ID != 0 {
+		return database.Upsert(record.ID, record)
+	}
+	return database.Insert(bolthold.NextSequence(), record)
+}
+
+// Retrieve a single auction by its ID
+func GetAuctionRecord(id uint64) (*AuctionRecord, error) {
+	record := &AuctionRecord{}
+	err := database.Get(id, record)
+	if err != nil {
+		return nil, err
+	}
+	record.ID = id
+	return record, nil
+}
+
+func findAuctionRecords(query *bolthold.Query, keep func(*AuctionRecord) bool) ([]AuctionRecord, error) {
+	var records []AuctionRecord
+	err := database.Find(&records, query)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]AuctionRecord, 0, len(records))
+	for i := range records {
+		if keep(&records[i]) {
+			result = append(result, records[i])
+		}
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Start.After(result[j].Start) })
+	return result, nil
+}
+
+// All auctions started at or after `since`, most recent first
+func AuctionHistory(since time.Time) ([]AuctionRecord, error) {
+	return findAuctionRecords(&bolthold.Query{}, func(record *AuctionRecord) bool {
+		return !record.Start.Before(since)
+	})
+}
+
+// All auctions of the named item, most recent first
+func AuctionsOfItem(itemName string) ([]AuctionRecord, error) {
+	return findAuctionRecords(bolthold.Where("ItemKey").Eq(strings.ToLower(itemName)).Index("ItemKey"),
+		func(*AuctionRecord) bool { return true })
+}
+
+// All auctions won by the named character, most recent first
+func AuctionsWonBy(charname string) ([]AuctionRecord, error) {
+	charname = strings.ToLower(charname)
+	return findAuctionRecords(&bolthold.Query{}, func(record *AuctionRecord) bool {
+		for _, award := range record.CurrentAwards() {
+			if strings.ToLower(award.Character) == charname {
+				return true
+			}
+		}
+		return false
+	})
+}
+
+// All auctions the named character bid on, most recent first
+func AuctionsBidOnBy(charname string) ([]AuctionRecord, error) {
+	charname = strings.ToLower(charname)
+	return findAuctionRecords(&bolthold.Query{}, func(record *AuctionRecord) bool {
+		for bidder := range record.Bids {
+			if strings.ToLower(bidder) == charname {
+				return true
+			}
+		}
+		return false
+	})
+}
@@ -0,0 +1,49 @@
+package storage
+
+import (
+	"github.com/timshannon/bolthold"
+	"sort"
+	"time"
+)
+
+// One of the items in a queued auction, and how many copies of it are up for grabs
+type QueuedItem struct {
+	ItemName string
+	Count    int
+}
+
+// An auction which has been requested, but not yet started.  Auctions of several items at once run in
+// parallel, with bidders picking an item by its position in `Items`.
+type QueuedAuction struct {
+	ID          uint64 `boltholdKey:"ID"`
+	Items       []QueuedItem
+	RequestedBy string
+	Queued      time.Time
+	Open        bool // Bid openly, with each new high bid announced, rather than by sealed bids
+}
+
+// Add an auction to the end of the queue
+func EnqueueAuction(qa *QueuedAuction) error {
+	return database.Insert(bolthold.NextSequence(), qa)
+}
+
+// Retrieve all pending auctions, oldest first
+func AuctionQueue() ([]QueuedAuction, error) {
+	var result []QueuedAuction
+	err := database.Find(&result, &bolthold.Query{})
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].ID < result[j].ID })
+	return result, nil
+}
+
+// Remove the specified auction from the queue
+func RemoveQueuedAuction(id uint64) error {
+	return database.Delete(id, &QueuedAuction{})
+}
+
+// Remove all pending auctions
+func ClearAuctionQueue() error {
+	return database.DeleteMatching(&QueuedAuction{}, &bolthold.Query{})
+}
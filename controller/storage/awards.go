@@ -0,0 +1,90 @@
+package storage
+
+import (
+	"errors"
+	"github.com/timshannon/bolthold"
+	bolt "go.etcd.io/bbolt"
+	"strings"
+	"time"
+)
+
+// A character who was finally awarded an item, and what they paid for it
+type Award struct {
+	Character string
+	Price     float64
+}
+
+// Who confirmed or changed the outcome of an auction, and how
+type AuditEntry struct {
+	When   time.Time
+	Who    string
+	Action string
+}
+
+// Note that `who` did `action` to this auction
+func (record *AuctionRecord) Audited(who string, action string) {
+	record.Audit = append(record.Audit, AuditEntry{When: time.Now(), Who: who, Action: action})
+}
+
+// The awards which would be made if the preliminary winners were confirmed as-is
+func (record *AuctionRecord) PreliminaryAwards() []Award {
+	awards := make([]Award, 0, len(record.Winners))
+	for _, winner := range record.Winners {
+		awards = append(awards, Award{Character: winner, Price: record.Price})
+	}
+	return awards
+}
+
+// The final awards if the auction has been confirmed, otherwise the preliminary ones
+func (record *AuctionRecord) CurrentAwards() []Award {
+	if record.Final {
+		return append([]Award{}, record.Awards...)
+	}
+	return record.PreliminaryAwards()
+}
+
+// Change a stored auction record.  The record is read, passed to `update`, and written back in a single
+// transaction, so that concurrent changes aren't lost.  If `update` returns an error, nothing is written.
+func UpdateAuctionRecord(id uint64, update func(record *AuctionRecord) error) (*AuctionRecord, error) {
+	record := &AuctionRecord{}
+	err := database.Bolt().Update(func(tx *bolt.Tx) error {
+		err := database.TxGet(tx, id, record)
+		if err != nil {
+			return err
+		}
+		record.ID = id
+		err = update(record)
+		if err != nil {
+			return err
+		}
+		record.ItemKey = strings.ToLower(record.ItemName)
+		return database.TxUpsert(tx, id, record)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return record, nil
+}
+
+// All auctions whose outcome hasn't been confirmed yet, most recent first
+func PendingAuctions() ([]AuctionRecord, error) {
+	return findAuctionRecords(&bolthold.Query{}, func(record *AuctionRecord) bool {
+		return !record.Final
+	})
+}
+
+// The auction whose result was posted as the given Discord message
+func AuctionByMessage(messageID string) (*AuctionRecord, error) {
+	if messageID == "" {
+		return nil, errors.New("no message ID")
+	}
+	records, err := findAuctionRecords(bolthold.Where("MessageID").Eq(messageID).Index("MessageID"),
+		func(*AuctionRecord) bool { return true })
+	if err != nil {
+		return nil, err
+	}
+	if len(records) == 0 {
+		return nil, bolthold.ErrNotFound
+	}
+	return &records[0], nil
+}
@@ -6,6 +6,8 @@ import (
 	"image/png"
 	"log"
 	"strconv"
+	"strings"
+	"time"
 )
 
 type BoltholdBackedConfig struct {
@@ -27,6 +29,16 @@ const (
 	rulesLuaKey     = "rulesLua"
 	useLinksKey     = "useLinks"
 	announceChanKey = "announceChannel"
+	auctionSchedKey = "auctionSchedule"
+	itemDataKey     = "itemData"
+	raidOnlyBidsKey = "raidOnlyBids"
+	dkpPolicyKey    = "dkpPolicy"
+	lastDecayKey    = "lastDecay"
+	lootersKey      = "looters"
+	extraLogsKey    = "extraLogs"
+	bossesKey       = "bosses"
+	bossKillDKPKey  = "bossKillDKP"
+	rosterChanKey   = "rosterChannel"
 )
 
 func (bhc *BoltholdBackedConfig) VoiceChannel() *VoiceChannel {
@@ -175,6 +187,23 @@ func (bhc *BoltholdBackedConfig) SetRulesLua(value string) {
 	}
 }
 
+func (bhc *BoltholdBackedConfig) ItemData() string {
+	value := &bhConfigEntry{}
+	err := database.Get(itemDataKey, value)
+	if err == nil {
+		return string(value.Data)
+	} else {
+		return ""
+	}
+}
+
+func (bhc *BoltholdBackedConfig) SetItemData(value string) {
+	err := database.Upsert(itemDataKey, &bhConfigEntry{[]byte(value)})
+	if err != nil {
+		log.Println(err)
+	}
+}
+
 func (bhc *BoltholdBackedConfig) TextChannel() string {
 	value := &bhConfigEntry{}
 	err := database.Get(textChannelKey, value)
@@ -215,6 +244,98 @@ func (bhc *BoltholdBackedConfig) SetUseLinks(value bool) {
 	}
 }
 
+func (bhc *BoltholdBackedConfig) RaidOnlyBids() bool {
+	value := &bhConfigEntry{}
+	err := database.Get(raidOnlyBidsKey, value)
+	if err == nil {
+		result, err2 := strconv.ParseBool(string(value.Data))
+		return err2 == nil && result
+	} else {
+		return false
+	}
+}
+
+func (bhc *BoltholdBackedConfig) SetRaidOnlyBids(value bool) {
+	err := database.Upsert(raidOnlyBidsKey, &bhConfigEntry{[]byte(strconv.FormatBool(value))})
+	if err != nil {
+		log.Println(err)
+	}
+}
+
+func (bhc *BoltholdBackedConfig) Looters() []string {
+	value := &bhConfigEntry{}
+	err := database.Get(lootersKey, value)
+	if err != nil {
+		return []string{}
+	}
+	return strings.Fields(string(value.Data))
+}
+
+func (bhc *BoltholdBackedConfig) SetLooters(value []string) {
+	err := database.Upsert(lootersKey, &bhConfigEntry{[]byte(strings.ToLower(strings.Join(value, " ")))})
+	if err != nil {
+		log.Println(err)
+	}
+}
+
+func (bhc *BoltholdBackedConfig) ExtraLogCharacters() []string {
+	value := &bhConfigEntry{}
+	err := database.Get(extraLogsKey, value)
+	if err != nil {
+		return []string{}
+	}
+	return strings.Fields(string(value.Data))
+}
+
+func (bhc *BoltholdBackedConfig) SetExtraLogCharacters(value []string) {
+	err := database.Upsert(extraLogsKey, &bhConfigEntry{[]byte(strings.ToLower(strings.Join(value, " ")))})
+	if err != nil {
+		log.Println(err)
+	}
+}
+
+func (bhc *BoltholdBackedConfig) Bosses() []string {
+	value := &bhConfigEntry{}
+	err := database.Get(bossesKey, value)
+	if err != nil {
+		return []string{}
+	}
+	result := make([]string, 0)
+	for _, boss := range strings.Split(string(value.Data), ",") {
+		if boss = strings.TrimSpace(boss); boss != "" {
+			result = append(result, boss)
+		}
+	}
+	return result
+}
+
+func (bhc *BoltholdBackedConfig) SetBosses(value []string) {
+	err := database.Upsert(bossesKey, &bhConfigEntry{[]byte(strings.Join(value, ","))})
+	if err != nil {
+		log.Println(err)
+	}
+}
+
+func (bhc *BoltholdBackedConfig) BossKillDKP() float64 {
+	value := &bhConfigEntry{}
+	err := database.Get(bossKillDKPKey, value)
+	if err != nil {
+		return 0
+	}
+	result, err := strconv.ParseFloat(string(value.Data), 64)
+	if err != nil {
+		return 0
+	}
+	return result
+}
+
+func (bhc *BoltholdBackedConfig) SetBossKillDKP(value float64) {
+	err := database.Upsert(bossKillDKPKey, &bhConfigEntry{[]byte(strconv.FormatFloat(value, 'f', -1, 64))})
+	if err != nil {
+		log.Println(err)
+	}
+}
+
 func (bhc *BoltholdBackedConfig) AnnounceChannel() string {
 	value := &bhConfigEntry{}
 	err := database.Get(announceChanKey, value)
@@ -231,3 +352,82 @@ func (bhc *BoltholdBackedConfig) SetAnnounceChannel(value string) {
 		log.Println(err)
 	}
 }
+
+func (bhc *BoltholdBackedConfig) AuctionSchedule() *AuctionSchedule {
+	value := &AuctionSchedule{}
+	err := database.Get(auctionSchedKey, value)
+	if err != nil {
+		return DefaultAuctionSchedule()
+	}
+	if value.OpenIdle == 0 {
+		// Saved before open auctions existed
+		value.OpenIdle = defaultOpenIdle
+		value.OpenIncrement = defaultOpenIncrement
+	}
+	return value
+}
+
+func (bhc *BoltholdBackedConfig) SetAuctionSchedule(value *AuctionSchedule) {
+	err := database.Upsert(auctionSchedKey, value)
+	if err != nil {
+		log.Println(err)
+	}
+}
+
+func (bhc *BoltholdBackedConfig) DKPPolicy() *DKPPolicy {
+	value := &DKPPolicy{}
+	err := database.Get(dkpPolicyKey, value)
+	if err != nil {
+		return &DKPPolicy{}
+	}
+	return value
+}
+
+func (bhc *BoltholdBackedConfig) SetDKPPolicy(value *DKPPolicy) {
+	err := database.Upsert(dkpPolicyKey, value)
+	if err != nil {
+		log.Println(err)
+	}
+}
+
+func (bhc *BoltholdBackedConfig) LastDecay() time.Time {
+	value := &bhConfigEntry{}
+	err := database.Get(lastDecayKey, value)
+	if err != nil {
+		return time.Time{}
+	}
+	result, err := time.Parse(time.RFC3339, string(value.Data))
+	if err != nil {
+		return time.Time{}
+	}
+	return result
+}
+
+func (bhc *BoltholdBackedConfig) SetLastDecay(value time.Time) {
+	err := database.Upsert(lastDecayKey, &bhConfigEntry{[]byte(value.Format(time.RFC3339))})
+	if err != nil {
+		log.Println(err)
+	}
+}
+
+func (bhc *BoltholdBackedConfig) RosterChannel() string {
+	value := &bhConfigEntry{}
+	err := database.Get(rosterChanKey, value)
+	if err == nil {
+		return string(value.Data)
+	} else {
+		return ""
+	}
+}
+
+func (bhc *BoltholdBackedConfig) SetRosterChannel(value string) {
+	var err error
+	if value == "" {
+		err = database.Delete(rosterChanKey, &bhConfigEntry{})
+	} else {
+		err = database.Upsert(rosterChanKey, &bhConfigEntry{[]byte(value)})
+	}
+	if err != nil {
+		log.Println(err)
+	}
+}
@@ -1,8 +1,12 @@
 package storage
 
 import (
+	"fmt"
 	"image"
+	"sort"
+	"strconv"
 	"strings"
+	"time"
 )
 
 type ControllerConfig interface {
@@ -14,7 +18,15 @@ type ControllerConfig interface {
 	DiscordToken() string
 	CloudTTSCredPath() string
 	RulesLua() string
+	ItemData() string
 	UseLinks() bool
+	RaidOnlyBids() bool
+	Looters() []string
+	ExtraLogCharacters() []string
+	Bosses() []string
+	BossKillDKP() float64
+	AuctionSchedule() *AuctionSchedule
+	DKPPolicy() *DKPPolicy
 
 	SetAnnounceChannel(string)
 	SetEverQuestDirectory(string)
@@ -23,16 +35,39 @@ type ControllerConfig interface {
 	SetDiscordToken(string)
 	SetCloudTTSCredPath(string)
 	SetRulesLua(string)
+	SetItemData(string)
 	SetUseLinks(bool)
+	SetRaidOnlyBids(bool)
+	SetLooters([]string)
+	SetExtraLogCharacters([]string)
+	SetBosses([]string)
+	SetBossKillDKP(float64)
+	SetAuctionSchedule(*AuctionSchedule)
+	SetDKPPolicy(*DKPPolicy)
 
 	// Settings established during run
 	ChannelImage() image.Image
 	TextChannel() string
 	VoiceChannel() *VoiceChannel
+	LastDecay() time.Time
+	RosterChannel() string
 
 	SetChannelImage(image.Image)
 	SetTextChannel(string)
 	SetVoiceChannel(*VoiceChannel)
+	SetLastDecay(time.Time)
+	SetRosterChannel(string)
+}
+
+// The bot character's name and server, from the "Bot character" setting (e.g. "Bidbot_xegony")
+func BotCharacter(cc ControllerConfig) (name string, server string) {
+	selected := cc.SelectedCharacter()
+	idx := strings.IndexByte(selected, '_')
+	if idx > 0 {
+		return selected[:idx], selected[idx+1:]
+	} else {
+		return selected, ""
+	}
 }
 
 type VoiceChannel struct {
@@ -49,3 +84,242 @@ func ChannelName(cc ControllerConfig) string {
 		return ""
 	}
 }
+
+// How long an auction runs, and when bidders are reminded that time is running out
+type AuctionSchedule struct {
+	Duration time.Duration   // Total length of the auction
+	Warnings []time.Duration // Amounts of time remaining at which to announce the auction again
+
+	// A new high bid with less than SnipeWindow remaining extends the auction so that at least
+	// SnipeExtend remains.  Zero turns this off.
+	SnipeWindow time.Duration
+	SnipeExtend time.Duration
+
+	// Open auctions close once OpenIdle passes without a new high bid, and each new high bid must beat
+	// the last one by at least OpenIncrement.
+	OpenIdle      time.Duration
+	OpenIncrement float64
+}
+
+const (
+	defaultOpenIdle      = 20 * time.Second
+	defaultOpenIncrement = 1
+)
+
+func DefaultAuctionSchedule() *AuctionSchedule {
+	return &AuctionSchedule{
+		Duration:      60 * time.Second,
+		Warnings:      []time.Duration{30 * time.Second, 10 * time.Second},
+		OpenIdle:      defaultOpenIdle,
+		OpenIncrement: defaultOpenIncrement,
+	}
+}
+
+// Warnings which fall within the auction, most time remaining first
+func (as *AuctionSchedule) SortedWarnings() []time.Duration {
+	result := make([]time.Duration, 0, len(as.Warnings))
+	for _, w := range as.Warnings {
+		if w > 0 && w < as.Duration {
+			result = append(result, w)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i] > result[j] })
+	return result
+}
+
+func formatSeconds(d time.Duration) string {
+	return strconv.Itoa(int(d / time.Second))
+}
+
+func parseSeconds(text string) (time.Duration, error) {
+	secs, err := strconv.Atoi(text)
+	if err != nil || secs < 0 {
+		return 0, fmt.Errorf("'%v' isn't a number of seconds", text)
+	}
+	return time.Duration(secs) * time.Second, nil
+}
+
+// Describe the auction length and warnings as text, e.g. "60 30 10"
+func (as *AuctionSchedule) TimelineText() string {
+	parts := []string{formatSeconds(as.Duration)}
+	for _, w := range as.Warnings {
+		parts = append(parts, formatSeconds(w))
+	}
+	return strings.Join(parts, " ")
+}
+
+// Describe the anti-snipe rule as text, e.g. "10/15", or "" when turned off
+func (as *AuctionSchedule) SnipeText() string {
+	if as.SnipeWindow == 0 || as.SnipeExtend == 0 {
+		return ""
+	}
+	return formatSeconds(as.SnipeWindow) + "/" + formatSeconds(as.SnipeExtend)
+}
+
+// Describe the open auction rule as text, e.g. "20/5"
+func (as *AuctionSchedule) OpenText() string {
+	return formatSeconds(as.OpenIdle) + "/" + strconv.FormatFloat(as.OpenIncrement, 'f', -1, 64)
+}
+
+// Parse an auction schedule from the text forms produced by TimelineText, SnipeText and OpenText
+func ParseAuctionSchedule(timeline string, snipe string, open string) (as *AuctionSchedule, err error) {
+	fields := strings.Fields(timeline)
+	if len(fields) == 0 {
+		return nil, fmt.Errorf("no auction length given")
+	}
+	result := &AuctionSchedule{
+		Warnings:      make([]time.Duration, 0),
+		OpenIdle:      defaultOpenIdle,
+		OpenIncrement: defaultOpenIncrement,
+	}
+	result.Duration, err = parseSeconds(fields[0])
+	if err != nil {
+		return
+	}
+	if result.Duration == 0 {
+		return nil, fmt.Errorf("auctions can't be zero seconds long")
+	}
+	for _, field := range fields[1:] {
+		var warning time.Duration
+		warning, err = parseSeconds(field)
+		if err != nil {
+			return
+		}
+		result.Warnings = append(result.Warnings, warning)
+	}
+	snipe = strings.TrimSpace(snipe)
+	if snipe != "" {
+		snipeParts := strings.Split(snipe, "/")
+		if len(snipeParts) != 2 {
+			return nil, fmt.Errorf("anti-snipe should look like <window>/<extension>")
+		}
+		result.SnipeWindow, err = parseSeconds(strings.TrimSpace(snipeParts[0]))
+		if err != nil {
+			return
+		}
+		result.SnipeExtend, err = parseSeconds(strings.TrimSpace(snipeParts[1]))
+		if err != nil {
+			return
+		}
+	}
+	open = strings.TrimSpace(open)
+	if open != "" {
+		openParts := strings.Split(open, "/")
+		if len(openParts) != 2 {
+			return nil, fmt.Errorf("open auction rule should look like <idle seconds>/<minimum raise>")
+		}
+		result.OpenIdle, err = parseSeconds(strings.TrimSpace(openParts[0]))
+		if err != nil {
+			return
+		}
+		if result.OpenIdle == 0 {
+			return nil, fmt.Errorf("open auctions can't close after zero seconds")
+		}
+		result.OpenIncrement, err = strconv.ParseFloat(strings.TrimSpace(openParts[1]), 64)
+		if err != nil || result.OpenIncrement < 0 {
+			return nil, fmt.Errorf("'%v' isn't a minimum raise", openParts[1])
+		}
+		err = nil
+	}
+	as = result
+	return
+}
+
+// How the DKP ledger's balances are managed between raids
+type DKPPolicy struct {
+	// Every DecayEvery, each balance shrinks by DecayPercent.  Zero turns this off.
+	DecayPercent float64
+	DecayEvery   time.Duration
+
+	// DKP spent on awards is shared out among the raiders present
+	ZeroSum bool
+
+	// Balances are kept between MinBalance and MaxBalance, when HasMin and HasMax are set
+	HasMin     bool
+	MinBalance float64
+	HasMax     bool
+	MaxBalance float64
+}
+
+const day = 24 * time.Hour
+
+func formatAmount(amount float64) string {
+	return strconv.FormatFloat(amount, 'f', -1, 64)
+}
+
+// Describe the decay rule as text, e.g. "10/7" for 10% every week, or "" when turned off
+func (dp *DKPPolicy) DecayText() string {
+	if dp.DecayPercent == 0 || dp.DecayEvery == 0 {
+		return ""
+	}
+	return formatAmount(dp.DecayPercent) + "/" + strconv.Itoa(int(dp.DecayEvery/day))
+}
+
+// Describe the balance limits as text, e.g. "0/1000", "/1000" or "" when there are none
+func (dp *DKPPolicy) CapsText() string {
+	if !dp.HasMin && !dp.HasMax {
+		return ""
+	}
+	text := ""
+	if dp.HasMin {
+		text = formatAmount(dp.MinBalance)
+	}
+	text += "/"
+	if dp.HasMax {
+		text += formatAmount(dp.MaxBalance)
+	}
+	return text
+}
+
+// Check whether any policy is turned on
+func (dp *DKPPolicy) Active() bool {
+	return dp.DecayText() != "" || dp.ZeroSum || dp.HasMin || dp.HasMax
+}
+
+// Parse DKP policies from the text forms produced by DecayText and CapsText
+func ParseDKPPolicy(decay string, caps string, zeroSum bool) (*DKPPolicy, error) {
+	result := &DKPPolicy{ZeroSum: zeroSum}
+	decay = strings.TrimSpace(decay)
+	if decay != "" {
+		decayParts := strings.Split(decay, "/")
+		if len(decayParts) != 2 {
+			return nil, fmt.Errorf("decay should look like <percent>/<days>")
+		}
+		percent, err := strconv.ParseFloat(strings.TrimSpace(decayParts[0]), 64)
+		if err != nil || percent < 0 || percent > 100 {
+			return nil, fmt.Errorf("'%v' isn't a percentage", decayParts[0])
+		}
+		days, err := strconv.Atoi(strings.TrimSpace(decayParts[1]))
+		if err != nil || days <= 0 {
+			return nil, fmt.Errorf("'%v' isn't a number of days", decayParts[1])
+		}
+		result.DecayPercent = percent
+		result.DecayEvery = time.Duration(days) * day
+	}
+	caps = strings.TrimSpace(caps)
+	if caps != "" {
+		capParts := strings.Split(caps, "/")
+		if len(capParts) != 2 {
+			return nil, fmt.Errorf("balance limits should look like <minimum>/<maximum>")
+		}
+		var err error
+		if text := strings.TrimSpace(capParts[0]); text != "" {
+			result.HasMin = true
+			result.MinBalance, err = strconv.ParseFloat(text, 64)
+			if err != nil {
+				return nil, fmt.Errorf("'%v' isn't a minimum balance", text)
+			}
+		}
+		if text := strings.TrimSpace(capParts[1]); text != "" {
+			result.HasMax = true
+			result.MaxBalance, err = strconv.ParseFloat(text, 64)
+			if err != nil {
+				return nil, fmt.Errorf("'%v' isn't a maximum balance", text)
+			}
+		}
+		if result.HasMin && result.HasMax && result.MinBalance > result.MaxBalance {
+			return nil, fmt.Errorf("the minimum balance is more than the maximum")
+		}
+	}
+	return result, nil
+}
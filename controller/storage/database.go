@@ -2,6 +2,7 @@ package storage
 
 import (
 	"github.com/timshannon/bolthold"
+	bolt "go.etcd.io/bbolt"
 	"os"
 )
 
@@ -22,3 +23,21 @@ func init() {
 		panic(err)
 	}
 }
+
+// Switch to a copy of the database at `path`, replacing any file already there, so that nothing stored from now on
+// changes the real database.  Used when replaying a log.
+func UseScratchCopy(path string) error {
+	err := database.Bolt().View(func(tx *bolt.Tx) error {
+		return tx.CopyFile(path, 0666)
+	})
+	if err != nil {
+		return err
+	}
+	scratch, err := bolthold.Open(path, 0666, nil)
+	if err != nil {
+		return err
+	}
+	database.Close()
+	database = scratch
+	return nil
+}
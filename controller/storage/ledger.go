@@ -0,0 +1,193 @@
+package storage
+
+import (
+	"errors"
+	"github.com/timshannon/bolthold"
+	bolt "go.etcd.io/bbolt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// What a DKP ledger entry was for
+const (
+	LedgerTick   = "tick"   // Attendance tick for everyone in the raid
+	LedgerBoss   = "boss"   // Boss kill for everyone in the raid
+	LedgerAdjust = "adjust" // Manual adjustment by an officer
+	LedgerAward  = "award"  // DKP spent on a confirmed auction award
+
+	LedgerZeroSum = "zerosum" // Share of the DKP spent on awards, under the zero-sum policy
+	LedgerDecay   = "decay"   // Periodic decay of the balance
+	LedgerCap     = "cap"     // Balance brought back within the minimum and maximum
+)
+
+// One change to a character's DKP.  Earnings are positive, spending negative.
+type LedgerEntry struct {
+	ID        uint64 `boltholdKey:"ID"`
+	When      time.Time
+	Character string `boltholdIndex:"Character"` // The main the DKP belongs to
+	Amount    float64
+	Kind      string
+	Reason    string
+	By        string
+	AuctionID uint64 `boltholdIndex:"AuctionID"` // For awards, the auction they came from
+	Shared    bool   // For awards, whether the DKP spent has been shared out under the zero-sum policy
+}
+
+// Record ledger entries, all or nothing
+func AddLedgerEntries(entries []LedgerEntry) error {
+	return database.Bolt().Update(func(tx *bolt.Tx) error {
+		for idx := range entries {
+			entries[idx].Character = strings.ToLower(entries[idx].Character)
+			err := database.TxInsert(tx, bolthold.NextSequence(), &entries[idx])
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Replace the spending recorded for an auction, e.g. when its award is reassigned
+func SetAuctionSpends(auctionID uint64, entries []LedgerEntry) error {
+	return database.Bolt().Update(func(tx *bolt.Tx) error {
+		var previous []LedgerEntry
+		query := bolthold.Where("AuctionID").Eq(auctionID).Index("AuctionID")
+		err := database.TxFind(tx, &previous, query)
+		if err != nil {
+			return err
+		}
+		// Spending which has already been shared out stays shared when the award changes hands.
+		shared := false
+		for _, entry := range previous {
+			shared = shared || entry.Shared
+		}
+		err = database.TxDeleteMatching(tx, &LedgerEntry{}, query)
+		if err != nil {
+			return err
+		}
+		for idx := range entries {
+			entries[idx].Character = strings.ToLower(entries[idx].Character)
+			entries[idx].Kind = LedgerAward
+			entries[idx].AuctionID = auctionID
+			entries[idx].Shared = shared
+			err = database.TxInsert(tx, bolthold.NextSequence(), &entries[idx])
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Award spending which hasn't been shared out under the zero-sum policy yet, oldest first
+func UnsharedSpends() ([]LedgerEntry, error) {
+	var entries []LedgerEntry
+	err := database.Find(&entries, bolthold.Where("Kind").Eq(LedgerAward).And("Shared").Eq(false))
+	if err != nil {
+		return nil, err
+	}
+	sortLedger(entries)
+	return entries, nil
+}
+
+// Returned by ApplyPolicyEntries when award spending they share out has changed since they were planned
+var ErrSpendsChanged = errors.New("award spending has changed since the DKP policies were planned")
+
+// Record the entries produced by the DKP policies, and mark the award spending they shared out, all or nothing.
+// If any of that spending has since been replaced or shared out, nothing is recorded and ErrSpendsChanged is
+// returned, so that the entries can be planned again.
+func ApplyPolicyEntries(entries []LedgerEntry, sharedIDs []uint64) error {
+	return database.Bolt().Update(func(tx *bolt.Tx) error {
+		for _, id := range sharedIDs {
+			spend := &LedgerEntry{}
+			err := database.TxGet(tx, id, spend)
+			if err == bolthold.ErrNotFound {
+				// Reassigned in the meantime
+				return ErrSpendsChanged
+			} else if err != nil {
+				return err
+			}
+			if spend.Shared {
+				return ErrSpendsChanged
+			}
+			spend.ID = id
+			spend.Shared = true
+			err = database.TxUpdate(tx, id, spend)
+			if err != nil {
+				return err
+			}
+		}
+		for idx := range entries {
+			entries[idx].Character = strings.ToLower(entries[idx].Character)
+			err := database.TxInsert(tx, bolthold.NextSequence(), &entries[idx])
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// A character's ledger entries, oldest first
+func LedgerEntriesOf(charname string) ([]LedgerEntry, error) {
+	var entries []LedgerEntry
+	err := database.Find(&entries, bolthold.Where("Character").Eq(strings.ToLower(charname)).Index("Character"))
+	if err != nil {
+		return nil, err
+	}
+	sortLedger(entries)
+	return entries, nil
+}
+
+// Every ledger entry, oldest first
+func LedgerEntries() ([]LedgerEntry, error) {
+	var entries []LedgerEntry
+	err := database.Find(&entries, &bolthold.Query{})
+	if err != nil {
+		return nil, err
+	}
+	sortLedger(entries)
+	return entries, nil
+}
+
+func sortLedger(entries []LedgerEntry) {
+	sort.Slice(entries, func(i, j int) bool {
+		if !entries[i].When.Equal(entries[j].When) {
+			return entries[i].When.Before(entries[j].When)
+		}
+		return entries[i].ID < entries[j].ID
+	})
+}
+
+// Check whether a character has anything in the ledger
+func InLedger(charname string) (bool, error) {
+	count, err := database.Count(&LedgerEntry{}, bolthold.Where("Character").Eq(strings.ToLower(charname)).Index("Character"))
+	return count != 0, err
+}
+
+// A character's DKP according to the ledger
+func LedgerBalance(charname string) (float64, error) {
+	entries, err := LedgerEntriesOf(charname)
+	if err != nil {
+		return 0, err
+	}
+	total := 0.0
+	for _, entry := range entries {
+		total += entry.Amount
+	}
+	return total, nil
+}
+
+// Every character's DKP according to the ledger
+func LedgerBalances() (map[string]float64, error) {
+	entries, err := LedgerEntries()
+	if err != nil {
+		return nil, err
+	}
+	result := make(map[string]float64)
+	for _, entry := range entries {
+		result[entry.Character] += entry.Amount
+	}
+	return result, nil
+}
@@ -0,0 +1,73 @@
+package storage
+
+import (
+	"errors"
+	"github.com/timshannon/bolthold"
+	bolt "go.etcd.io/bbolt"
+	"sort"
+	"time"
+)
+
+// An item picked up by one of the looters
+type LootRecord struct {
+	ID        uint64 `boltholdKey:"ID"`
+	Looted    time.Time
+	Looter    string
+	ItemName  string
+	Count     int
+	MessageID string `boltholdIndex:"MessageID"` // Discord message offering the item for auction
+	Handled   bool   // Queued for auction, or dismissed
+}
+
+// Store a new loot record, filling in its ID
+func SaveLootRecord(record *LootRecord) error {
+	return database.Insert(bolthold.NextSequence(), record)
+}
+
+// Change a loot record in place, returning the result
+func UpdateLootRecord(id uint64, update func(record *LootRecord) error) (*LootRecord, error) {
+	record := &LootRecord{}
+	err := database.Bolt().Update(func(tx *bolt.Tx) error {
+		err := database.TxGet(tx, id, record)
+		if err != nil {
+			return err
+		}
+		record.ID = id
+		err = update(record)
+		if err != nil {
+			return err
+		}
+		return database.TxUpsert(tx, id, record)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return record, nil
+}
+
+// Loot which hasn't been auctioned or dismissed, oldest first
+func UnhandledLoot() ([]LootRecord, error) {
+	var records []LootRecord
+	err := database.Find(&records, bolthold.Where("Handled").Eq(false))
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(records, func(i, j int) bool { return records[i].ID < records[j].ID })
+	return records, nil
+}
+
+// The loot offered for auction in the given Discord message
+func LootByMessage(messageID string) (*LootRecord, error) {
+	if messageID == "" {
+		return nil, errors.New("no message ID")
+	}
+	var records []LootRecord
+	err := database.Find(&records, bolthold.Where("MessageID").Eq(messageID).Index("MessageID"))
+	if err != nil {
+		return nil, err
+	}
+	if len(records) == 0 {
+		return nil, bolthold.ErrNotFound
+	}
+	return &records[0], nil
+}
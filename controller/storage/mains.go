@@ -0,0 +1,73 @@
+package storage
+
+import (
+	"github.com/timshannon/bolthold"
+	"sort"
+	"strings"
+	"time"
+)
+
+// An officer's say on whose alt a character is, taking precedence over the guild note
+type MainOverride struct {
+	Alt   string `boltholdKey:"Alt"`
+	Main  string `boltholdIndex:"Main"`
+	SetBy string
+	When  time.Time
+}
+
+// Record that `alt` belongs to `main`, replacing any earlier override
+func SetMainOverride(alt string, main string, setBy string) error {
+	alt = strings.ToLower(alt)
+	return database.Upsert(alt, &MainOverride{
+		Alt:   alt,
+		Main:  strings.ToLower(main),
+		SetBy: strings.ToLower(setBy),
+		When:  time.Now(),
+	})
+}
+
+// Remove the override for `alt`.  Returns false if there wasn't one.
+func ClearMainOverride(alt string) (bool, error) {
+	err := database.Delete(strings.ToLower(alt), &MainOverride{})
+	if err == bolthold.ErrNotFound {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// The main `alt` has been overridden to belong to, or nil if there's no override
+func MainOverrideOf(alt string) (*MainOverride, error) {
+	override := &MainOverride{}
+	err := database.Get(strings.ToLower(alt), override)
+	if err == bolthold.ErrNotFound {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	return override, nil
+}
+
+// The overrides pointing alts at `main`, in alphabetical order of alt
+func OverriddenAltsOf(main string) ([]MainOverride, error) {
+	var overrides []MainOverride
+	err := database.Find(&overrides, bolthold.Where("Main").Eq(strings.ToLower(main)).Index("Main"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(overrides, func(i, j int) bool { return overrides[i].Alt < overrides[j].Alt })
+	return overrides, nil
+}
+
+// Every override, as a map from alt to main
+func MainOverrides() (map[string]string, error) {
+	var overrides []MainOverride
+	err := database.Find(&overrides, &bolthold.Query{})
+	if err != nil {
+		return nil, err
+	}
+	result := make(map[string]string, len(overrides))
+	for _, override := range overrides {
+		result[override.Alt] = override.Main
+	}
+	return result, nil
+}
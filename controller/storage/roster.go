@@ -0,0 +1,81 @@
+package storage
+
+import (
+	"github.com/timshannon/bolthold"
+	bolt "go.etcd.io/bbolt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// What a guild roster change was
+const (
+	RosterJoined = "joined"
+	RosterLeft   = "left"
+	RosterRank   = "rank"
+	RosterAlt    = "alt"
+	RosterNote   = "note"
+)
+
+// The parts of a guild member's record which are tracked for changes
+type RosterMember struct {
+	Rank      string
+	IsAlt     bool
+	GuildNote string
+}
+
+// The guild roster as of the last guild dump
+type RosterSnapshot struct {
+	Taken   time.Time
+	Members map[string]RosterMember
+}
+
+// One change between successive guild dumps.  Old and New hold the rank, "alt"/"main" or guild note, as
+// appropriate to Kind.
+type RosterChange struct {
+	ID        uint64 `boltholdKey:"ID"`
+	When      time.Time
+	Character string `boltholdIndex:"Character"`
+	Kind      string
+	Old       string
+	New       string
+}
+
+const rosterSnapshotKey = "guildRoster"
+
+// The roster as of the last guild dump, or nil if there hasn't been one
+func LastRoster() (*RosterSnapshot, error) {
+	snapshot := &RosterSnapshot{}
+	err := database.Get(rosterSnapshotKey, snapshot)
+	if err == bolthold.ErrNotFound {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	return snapshot, nil
+}
+
+// Store a new roster along with the changes since the last one, all or nothing
+func SaveRoster(snapshot *RosterSnapshot, changes []RosterChange) error {
+	return database.Bolt().Update(func(tx *bolt.Tx) error {
+		for idx := range changes {
+			changes[idx].Character = strings.ToLower(changes[idx].Character)
+			err := database.TxInsert(tx, bolthold.NextSequence(), &changes[idx])
+			if err != nil {
+				return err
+			}
+		}
+		return database.TxUpsert(tx, rosterSnapshotKey, snapshot)
+	})
+}
+
+// The roster changes of a character, oldest first
+func RosterChangesOf(charname string) ([]RosterChange, error) {
+	var changes []RosterChange
+	err := database.Find(&changes, bolthold.Where("Character").Eq(strings.ToLower(charname)).Index("Character"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(changes, func(i, j int) bool { return changes[i].ID < changes[j].ID })
+	return changes, nil
+}
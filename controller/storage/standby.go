@@ -0,0 +1,49 @@
+package storage
+
+import (
+	"github.com/timshannon/bolthold"
+	"sort"
+	"strings"
+)
+
+// A character officers have allowed to bid while outside the raid, e.g. someone on the bench
+type StandbyRecord struct {
+	Name string `boltholdKey:"Name"`
+}
+
+// Allow a character to bid in raid-only auctions while outside the raid
+func AddStandby(charname string) error {
+	charname = strings.ToLower(charname)
+	return database.Upsert(charname, &StandbyRecord{Name: charname})
+}
+
+// Take a character off the standby list.  Returns false if they weren't on it.
+func RemoveStandby(charname string) (bool, error) {
+	charname = strings.ToLower(charname)
+	err := database.Delete(charname, &StandbyRecord{})
+	if err == bolthold.ErrNotFound {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// The characters on the standby list, in alphabetical order
+func StandbyList() ([]string, error) {
+	var records []StandbyRecord
+	err := database.Find(&records, &bolthold.Query{})
+	if err != nil {
+		return nil, err
+	}
+	result := make([]string, 0, len(records))
+	for _, record := range records {
+		result = append(result, record.Name)
+	}
+	sort.Strings(result)
+	return result, nil
+}
+
+// Check whether a character is on the standby list
+func IsStandby(charname string) bool {
+	err := database.Get(strings.ToLower(charname), &StandbyRecord{})
+	return err == nil
+}
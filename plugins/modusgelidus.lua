@@ -38,8 +38,12 @@ local function initial_cap(name)
     return name:sub(1,1):upper()..name:sub(2):lower()
 end
 
--- Determine if the given character is an alt by looking at a guild dump.
+-- Determine if the given character is an alt by looking at the !setmain overrides, then a guild dump.
 local function isalt(charname, guilddump)
+    local override = eq.mainoverrides()[charname]
+    if override~=nil then
+        return override~=charname
+    end
     record = guilddump[charname]
     return record~=nil and (
                     record.alt or
@@ -66,7 +70,13 @@ end
 -- Return the name of the character who should be charged when `charname` wins something.
 -- While the spec expects only 1 argument, we define a function taking 2 arguments here. When called with one argument,
 -- we will just get nil for the extra arguments, and will make calls to fill them
+-- BidBot2 checks the overrides set with !setmain before calling this, but calls from within this script (like the
+-- one in sortbids) have to look at eq.mainoverrides(), a table of main names keyed by alt, themselves.
 function getmain(charname, guilddump)
+    local override = eq.mainoverrides()[charname]
+    if override~=nil then
+        return override
+    end
     if guilddump==nil then
         guilddump=eq.guildmembers()
     end
@@ -80,6 +90,9 @@ end
 -- Get the currently posted DKP for the specified character.
 -- While the spec expects only 1 argument, we define a function taking 3 arguments here. When called with one argument,
 -- we will just get nil for the extra arguments, and will make calls to fill them
+-- Guilds keeping DKP in BidBot2's own ledger (see !tick and !adjust) can look it up instead of scraping:
+--     local ledger = require "ledger"
+--     return ledger.balance(getmain(charname))
 function getdkp(charname, alldkp, guilddump)
     if alldkp==nil then
         alldkp=getalldkp()
@@ -94,6 +107,20 @@ end
 
 -- Determine if the `charname` is allowed to bid `quantity`, returning a description of the problem
 -- if they're not allowed to bid.
+-- BidBot2 also passes a third argument describing the auction, which this script doesn't need: a table with
+-- - item: name of the item being auctioned
+-- - count: number of copies being auctioned
+-- - zone: zone the auction was started in, or "" if unknown
+-- - boss: configured boss killed within the hour before the auction, or "" if none
+-- - startedby: character who started the auction
+-- - start: time the auction started, in seconds since 1970
+-- sortbids receives the same table as its third argument.
+-- With an item data file loaded, the `items` module can reject bids from classes which can't use the item:
+--     local items = require "items"
+--     if auction ~= nil and not items.usableby(auction.item, class_of(charname)) then
+--         return "Your class can't use "..auction.item.."."
+--     end
+-- items.find(name) returns a table with id, name, slots, classes, races, lore and nodrop, or nil.
 function validatebid(charname, quantity)
     if quantity<minimum_bid then
         return "You must bid at least "..minimum_bid.." DKP."
@@ -104,6 +131,41 @@ function validatebid(charname, quantity)
     end
 end
 
+-- Optional: decide how long the auction of `item` should run.  Return nil to use the timeline configured in
+-- BidBot2, or a table overriding any of:
+-- - duration: length of the auction, in seconds
+-- - warnings: list of seconds remaining at which to announce the auction again
+-- - snipewindow, snipeextend: a new high bid with less than snipewindow seconds left extends the auction to
+--   snipeextend seconds left
+-- - openidle, openincrement: open auctions ("!auc open") close after openidle seconds without a new high bid,
+--   and each new high bid must beat the last by at least openincrement
+-- function auctionschedule(item, count)
+--     return {duration=90, warnings={45, 15}, snipewindow=10, snipeextend=15}
+-- end
+
+-- Optional: decide what a tell from `charname` means as a bid.  `auction` describes the auction, as for
+-- validatebid.  Return one of:
+-- - a number: the bid (0 cancels a previous bid)
+-- - "cancel", or "all"/"max" to bid everything the character has
+-- - nil and a message: the tell isn't an acceptable bid, and the message is sent back to the bidder
+-- - nil: let BidBot2 make sense of the tell (numbers, "all", "max" and "cancel" are understood)
+-- function parsebid(charname, text, auction)
+--     if string.match(text, "for my alt") then
+--         return nil, "Please send alt bids from the alt."
+--     end
+--     return nil
+-- end
+
+-- Optional: decide what to do with an item picked up by one of the looters named in BidBot2's settings.
+-- Return true to queue an auction of it straight away, false to leave it be, or nil to post it in Discord for
+-- the officers to decide.
+-- function shouldauction(item, looter)
+--     if string.match(item, "^Spell: ") then
+--         return true
+--     end
+--     return nil
+-- end
+
 -- Determine the winner(s) of an auction, and how to display the outcome.
 -- Accepts
 -- - bids: table mapping bidder (string) to bid (number)